@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+func buildChain(adapters []int, maxGap int) ([]int, error) {
+	if maxGap < 1 {
+		return nil, errors.New(fmt.Sprintf("maximum gap must be at least 1, got %d", maxGap))
+	}
+	if len(adapters) == 0 {
+		return nil, errors.New("no adapters to chain")
+	}
+
+	chain := append([]int{0}, adapters...)
+	sort.Ints(chain)
+	chain = append(chain, chain[len(chain)-1]+maxGap)
+
+	for i := 1; i < len(chain); i++ {
+		gap := chain[i] - chain[i-1]
+		if gap < 1 {
+			return nil, errors.New(fmt.Sprintf("adapter %d is not above the previous joltage %d", chain[i], chain[i-1]))
+		}
+		if gap > maxGap {
+			return nil, errors.New(fmt.Sprintf("gap of %d between %d and %d jolts exceeds the maximum of %d", gap, chain[i-1], chain[i], maxGap))
+		}
+	}
+
+	return chain, nil
+}
+
+func countArrangements(adapters []int, maxGap int) (*big.Int, error) {
+	chain, err := buildChain(adapters, maxGap)
+	if err != nil {
+		return nil, err
+	}
+
+	ways := make([]*big.Int, len(chain))
+	ways[0] = big.NewInt(1)
+	window := big.NewInt(1)
+	low := 0
+
+	for i := 1; i < len(chain); i++ {
+		for chain[i]-chain[low] > maxGap {
+			window.Sub(window, ways[low])
+			low++
+		}
+
+		ways[i] = new(big.Int).Set(window)
+		window.Add(window, ways[i])
+	}
+
+	return ways[len(ways)-1], nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+type Explorer struct {
+	Chain  []int
+	MaxGap int
+	suffix []*big.Int
+}
+
+func newExplorer(adapters []int, maxGap int) (*Explorer, error) {
+	chain, err := buildChain(adapters, maxGap)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Explorer{
+		Chain:  chain,
+		MaxGap: maxGap,
+		suffix: make([]*big.Int, len(chain)),
+	}
+
+	last := len(chain) - 1
+	e.suffix[last] = big.NewInt(1)
+	for i := last - 1; i >= 0; i-- {
+		e.suffix[i] = new(big.Int)
+		for _, next := range e.nextSteps(i) {
+			e.suffix[i].Add(e.suffix[i], e.suffix[next])
+		}
+	}
+
+	return e, nil
+}
+
+func (e *Explorer) nextSteps(i int) []int {
+	steps := []int{}
+	for j := i + 1; j < len(e.Chain) && e.Chain[j]-e.Chain[i] <= e.MaxGap; j++ {
+		steps = append(steps, j)
+	}
+
+	return steps
+}
+
+func (e *Explorer) Count() *big.Int {
+	return new(big.Int).Set(e.suffix[0])
+}
+
+func (e *Explorer) Arrangements(limit int, visit func([]int) bool) {
+	emitted := 0
+	path := []int{0}
+
+	var walk func(i int) bool
+	walk = func(i int) bool {
+		if i == len(e.Chain)-1 {
+			emitted++
+			return visit(e.joltages(path)) && (limit <= 0 || emitted < limit)
+		}
+
+		for _, next := range e.nextSteps(i) {
+			path = append(path, next)
+			more := walk(next)
+			path = path[:len(path)-1]
+			if !more {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	walk(0)
+}
+
+func (e *Explorer) Sample(rng *rand.Rand) []int {
+	path := []int{0}
+	i := 0
+	for i < len(e.Chain)-1 {
+		pick := new(big.Int).Rand(rng, e.suffix[i])
+		for _, next := range e.nextSteps(i) {
+			if pick.Cmp(e.suffix[next]) < 0 {
+				i = next
+				break
+			}
+			pick.Sub(pick, e.suffix[next])
+		}
+		path = append(path, i)
+	}
+
+	return e.joltages(path)
+}
+
+func (e *Explorer) joltages(path []int) []int {
+	joltages := make([]int, len(path))
+	for i, index := range path {
+		joltages[i] = e.Chain[index]
+	}
+
+	return joltages
+}
+
+type GapError struct {
+	Index int
+	From  int
+	To    int
+	Max   int
+}
+
+func (g *GapError) Error() string {
+	return fmt.Sprintf("gap of %d jolts between %d and %d (position %d) exceeds the maximum of %d", g.To-g.From, g.From, g.To, g.Index, g.Max)
+}
+
+func histogram(chain []int, maxGap int) (map[int]int, error) {
+	diffs := map[int]int{}
+	for i := 1; i < len(chain); i++ {
+		diff := chain[i] - chain[i-1]
+		if diff < 1 || diff > maxGap {
+			return nil, &GapError{Index: i, From: chain[i-1], To: chain[i], Max: maxGap}
+		}
+		diffs[diff]++
+	}
+
+	return diffs, nil
+}
+
+func formatHistogram(diffs map[int]int) string {
+	gaps := []int{}
+	widest := 0
+	for gap, count := range diffs {
+		gaps = append(gaps, gap)
+		if count > widest {
+			widest = count
+		}
+	}
+	sort.Ints(gaps)
+
+	var sb strings.Builder
+	for _, gap := range gaps {
+		bar := diffs[gap]
+		if widest > 50 {
+			bar = bar * 50 / widest
+		}
+		sb.WriteString(fmt.Sprintf("%3d | %-50s %d\n", gap, strings.Repeat("#", bar), diffs[gap]))
+	}
+
+	return sb.String()
+}
+
+func parseChain(input string) ([]int, error) {
+	chain := []int{}
+	for _, field := range strings.Split(input, ",") {
+		var joltage int
+		if _, err := fmt.Sscan(strings.TrimSpace(field), &joltage); err != nil {
+			return nil, errors.New(fmt.Sprintf("invalid joltage %q in chain", field))
+		}
+		chain = append(chain, joltage)
+	}
+
+	return chain, nil
+}
@@ -2,13 +2,15 @@ package main
 
 import (
 	"bufio"
-	"errors"
+	"flag"
 	"fmt"
 	"log"
-	"math"
+	"math/big"
+	"math/rand"
 	"os"
 	"sort"
 	"strconv"
+	"strings"
 )
 
 func getNumbers() (numbers []int, err error) {
@@ -37,62 +39,73 @@ func getNumbers() (numbers []int, err error) {
 }
 
 func part1(numbers []int) (int, error) {
-	diffs := map[int]int{
-		1: 0,
-		2: 0,
-		3: 1,
+	chain, err := buildChain(numbers, 3)
+	if err != nil {
+		return 0, err
 	}
 
-	last := 0
-	for i := 0; i < len(numbers); i++ {
-		diff := numbers[i] - last
-		if diff > 3 {
-			return 0, errors.New("difference was greater than 3")
-		}
-		diffs[diff]++
-		last = numbers[i]
+	diffs, err := histogram(chain, 3)
+	if err != nil {
+		return 0, err
 	}
 
 	return diffs[1] * diffs[3], nil
 }
 
-func part2(numbers []int) int {
-	numbers = append([]int{0}, numbers...)
-	numbers = append(numbers, numbers[len(numbers)-1]+3)
-
-	combos := 1
-	setLens := map[int]int{}
-	buffer := []int{}
-	low := -1
-
-	for i := 1; i < len(numbers); i++ {
-		if numbers[i]-numbers[i-1] < 3 && numbers[i+1]-numbers[i] < 3 {
-			if low == -1 {
-				low = numbers[i-1]
-			}
-			buffer = append(buffer, numbers[i])
-		} else {
-			if len(buffer) > 0 {
-				possible := int(math.Pow(2, float64(len(buffer))))
-				if numbers[i]-low > 3 {
-					possible = possible - 1
-				}
-
-				setLens[possible]++
-				buffer = []int{}
-				low = -1
-			}
+func part2(numbers []int, maxGap int) (*big.Int, error) {
+	return countArrangements(numbers, maxGap)
+}
+
+func explore(numbers []int, maxGap int, list int, sample bool, seed int64) error {
+	explorer, err := newExplorer(numbers, maxGap)
+	if err != nil {
+		return err
+	}
+
+	if list > 0 {
+		explorer.Arrangements(list, func(arrangement []int) bool {
+			fmt.Println(strings.Trim(fmt.Sprint(arrangement), "[]"))
+			return true
+		})
+	}
+
+	if sample {
+		arrangement := explorer.Sample(rand.New(rand.NewSource(seed)))
+		fmt.Println(strings.Trim(fmt.Sprint(arrangement), "[]"))
+
+		diffs, err := histogram(arrangement, maxGap)
+		if err != nil {
+			return err
 		}
+		fmt.Print(formatHistogram(diffs))
+	}
+
+	return nil
+}
+
+func printChain(input string, maxGap int) error {
+	chain, err := parseChain(input)
+	if err != nil {
+		return err
 	}
 
-	for length, count := range setLens {
-		combos *= int(math.Pow(float64(length), float64(count)))
+	diffs, err := histogram(chain, maxGap)
+	if err != nil {
+		return err
 	}
 
-	return combos
+	fmt.Print(formatHistogram(diffs))
+	return nil
 }
 
 func main() {
+	maxGap := flag.Int("max-gap", 3, "largest joltage difference an adapter can accept")
+	list := flag.Int("list", 0, "list up to this many arrangements")
+	sample := flag.Bool("sample", false, "print a uniformly random arrangement and its difference histogram")
+	seed := flag.Int64("seed", 1, "random seed used by -sample")
+	chain := flag.String("chain", "", "print the difference histogram of a comma-separated chain of joltages")
+	flag.Parse()
+
 	numbers, err := getNumbers()
 	sort.Ints(numbers)
 	if err != nil {
@@ -106,6 +119,22 @@ func main() {
 
 	log.Println(fmt.Sprintf("The product of the difference is %d in part 1", product))
 
-	sets := part2(numbers)
-	log.Println(fmt.Sprintf("There are %d possible sets in part 2", sets))
+	sets, err := part2(numbers, *maxGap)
+	if err != nil {
+		log.Fatalln(fmt.Sprintf("Error counting arrangements in part 2: %s", err))
+	}
+
+	log.Println(fmt.Sprintf("There are %s possible sets in part 2", sets))
+
+	if *list > 0 || *sample {
+		if err := explore(numbers, *maxGap, *list, *sample, *seed); err != nil {
+			log.Fatalln(fmt.Sprintf("Error exploring arrangements: %s", err))
+		}
+	}
+
+	if *chain != "" {
+		if err := printChain(*chain, *maxGap); err != nil {
+			log.Fatalln(fmt.Sprintf("Error reading chain: %s", err))
+		}
+	}
 }
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+)
+
+const (
+	Floor    int8 = -1
+	Empty    int8 = 0
+	Occupied int8 = 1
+)
+
+type Grid struct {
+	Rows  int
+	Seats int
+	Cells []int8
+}
+
+type Neighbourhood func(grid *Grid, row int, seat int) []int
+
+type Automaton struct {
+	Rules          Rules
+	Workers        int
+	MaxGenerations int
+	Generation     int
+	Changed        int
+	current        *Grid
+	next           *Grid
+	neighbours     *neighbourTable
+}
+
+func newGrid(rows int, seats int) *Grid {
+	return &Grid{
+		Rows:  rows,
+		Seats: seats,
+		Cells: make([]int8, rows*seats),
+	}
+}
+
+func gridFromLayout(layout [][]int) *Grid {
+	seats := 0
+	if len(layout) > 0 {
+		seats = len(layout[0])
+	}
+
+	grid := newGrid(len(layout), seats)
+	for row, cells := range layout {
+		for seat, cell := range cells {
+			grid.Set(row, seat, int8(cell))
+		}
+	}
+
+	return grid
+}
+
+func (g *Grid) Contains(row int, seat int) bool {
+	return row >= 0 && row < g.Rows && seat >= 0 && seat < g.Seats
+}
+
+func (g *Grid) Index(row int, seat int) int {
+	return row*g.Seats + seat
+}
+
+func (g *Grid) At(row int, seat int) int8 {
+	return g.Cells[row*g.Seats+seat]
+}
+
+func (g *Grid) Set(row int, seat int, cell int8) {
+	g.Cells[row*g.Seats+seat] = cell
+}
+
+func (g *Grid) Occupied() int {
+	occ := 0
+	for _, cell := range g.Cells {
+		if cell == Occupied {
+			occ++
+		}
+	}
+
+	return occ
+}
+
+func (g *Grid) Layout() [][]int {
+	layout := make([][]int, g.Rows)
+	for row := range layout {
+		layout[row] = make([]int, g.Seats)
+		for seat := range layout[row] {
+			layout[row][seat] = int(g.At(row, seat))
+		}
+	}
+
+	return layout
+}
+
+func adjacent(grid *Grid, row int, seat int) []int {
+	neighbours := []int{}
+	for _, adj := range seatMovement {
+		adjRow := row + adj.Row
+		adjSeat := seat + adj.Seat
+		if grid.Contains(adjRow, adjSeat) && grid.At(adjRow, adjSeat) != Floor {
+			neighbours = append(neighbours, grid.Index(adjRow, adjSeat))
+		}
+	}
+
+	return neighbours
+}
+
+func lineOfSight(grid *Grid, row int, seat int) []int {
+	neighbours := []int{}
+	for _, adj := range seatMovement {
+		adjRow := row + adj.Row
+		adjSeat := seat + adj.Seat
+		for grid.Contains(adjRow, adjSeat) && grid.At(adjRow, adjSeat) == Floor {
+			adjRow += adj.Row
+			adjSeat += adj.Seat
+		}
+
+		if grid.Contains(adjRow, adjSeat) {
+			neighbours = append(neighbours, grid.Index(adjRow, adjSeat))
+		}
+	}
+
+	return neighbours
+}
+
+type neighbourTable struct {
+	offsets []int32
+	cells   []int32
+}
+
+func buildNeighbourTable(grid *Grid, neighbourhood Neighbourhood) *neighbourTable {
+	table := &neighbourTable{
+		offsets: make([]int32, len(grid.Cells)+1),
+	}
+
+	for row := 0; row < grid.Rows; row++ {
+		for seat := 0; seat < grid.Seats; seat++ {
+			index := grid.Index(row, seat)
+			if grid.Cells[index] != Floor {
+				for _, neighbour := range neighbourhood(grid, row, seat) {
+					table.cells = append(table.cells, int32(neighbour))
+				}
+			}
+			table.offsets[index+1] = int32(len(table.cells))
+		}
+	}
+
+	return table
+}
+
+func (t *neighbourTable) occupied(grid *Grid, index int) int {
+	occupiedAdj := 0
+	for _, neighbour := range t.cells[t.offsets[index]:t.offsets[index+1]] {
+		if grid.Cells[neighbour] == Occupied {
+			occupiedAdj++
+		}
+	}
+
+	return occupiedAdj
+}
+
+func (r Rules) transition(occupied int, occupiedAdj int) int {
+	if r.SitOrStand != nil {
+		return r.SitOrStand(occupied, occupiedAdj)
+	}
+
+	switch occupied {
+	case 0:
+		if occupiedAdj == 0 {
+			return 1
+		}
+	case 1:
+		if occupiedAdj >= r.Threshold {
+			return 0
+		}
+	}
+
+	return occupied
+}
+
+func newAutomaton(grid *Grid, rules Rules) *Automaton {
+	current := grid.Clone()
+
+	if rules.Neighbourhood == nil {
+		rules.Neighbourhood = adjacent
+	}
+
+	return &Automaton{
+		Rules:      rules,
+		current:    current,
+		next:       newGrid(grid.Rows, grid.Seats),
+		neighbours: buildNeighbourTable(current, rules.Neighbourhood),
+	}
+}
+
+func (a *Automaton) Grid() *Grid {
+	return a.current
+}
+
+func (a *Automaton) Step() int {
+	if a.Workers > 1 && a.current.Rows > 1 {
+		a.Changed = a.stepParallel()
+	} else {
+		a.Changed = a.stepRows(0, a.current.Rows)
+	}
+	a.current, a.next = a.next, a.current
+	a.Generation++
+
+	return a.Changed
+}
+
+func (a *Automaton) stepRows(from int, to int) int {
+	changed := 0
+	for index := from * a.current.Seats; index < to*a.current.Seats; index++ {
+		cell := a.current.Cells[index]
+		next := cell
+		if cell != Floor {
+			next = int8(a.Rules.transition(int(cell), a.neighbours.occupied(a.current, index)))
+		}
+
+		if next != cell {
+			changed++
+		}
+		a.next.Cells[index] = next
+	}
+
+	return changed
+}
+
+func (a *Automaton) stepParallel() int {
+	workers := a.Workers
+	if workers > a.current.Rows {
+		workers = a.current.Rows
+	}
+
+	changes := make([]int, workers)
+	band := (a.current.Rows + workers - 1) / workers
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < workers; worker++ {
+		from := worker * band
+		to := from + band
+		if to > a.current.Rows {
+			to = a.current.Rows
+		}
+
+		wg.Add(1)
+		go func(worker int, from int, to int) {
+			defer wg.Done()
+			changes[worker] = a.stepRows(from, to)
+		}(worker, from, to)
+	}
+	wg.Wait()
+
+	changed := 0
+	for _, c := range changes {
+		changed += c
+	}
+
+	return changed
+}
+
+func (a *Automaton) Previous() *Grid {
+	return a.next
+}
+
+type CycleError struct {
+	Start  int
+	Period int
+}
+
+func (c *CycleError) Error() string {
+	return fmt.Sprintf("layout oscillates with period %d starting at generation %d", c.Period, c.Start)
+}
+
+func (g *Grid) Hash() uint64 {
+	h := fnv.New64a()
+	buf := make([]byte, len(g.Cells))
+	for i, cell := range g.Cells {
+		buf[i] = byte(cell)
+	}
+	h.Write(buf)
+
+	return h.Sum64()
+}
+
+func (a *Automaton) Run() (*Grid, error) {
+	return a.RunWith(nil)
+}
+
+func (g *Grid) Clone() *Grid {
+	clone := newGrid(g.Rows, g.Seats)
+	copy(clone.Cells, g.Cells)
+
+	return clone
+}
+
+func (g *Grid) Equal(other *Grid) bool {
+	if g.Rows != other.Rows || g.Seats != other.Seats {
+		return false
+	}
+
+	for i := range g.Cells {
+		if g.Cells[i] != other.Cells[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// replay steps a fresh automaton from initial, taken at generation base, up to
+// generation target and returns its grid.
+func (a *Automaton) replay(initial *Grid, base int, target int) *Grid {
+	replayed := newAutomaton(initial, a.Rules)
+	for replayed.Generation < target-base {
+		replayed.Step()
+	}
+
+	return replayed.Grid()
+}
+
+func (a *Automaton) RunWith(visit func(a *Automaton)) (*Grid, error) {
+	base := a.Generation
+	initial := a.current.Clone()
+	seen := map[uint64]int{a.current.Hash(): a.Generation}
+	if visit != nil {
+		visit(a)
+	}
+
+	for a.Step() > 0 {
+		if visit != nil {
+			visit(a)
+		}
+
+		// Hashes only point at a candidate; the earlier generation is replayed
+		// and compared so a collision cannot be reported as a cycle.
+		hash := a.current.Hash()
+		if start, ok := seen[hash]; ok && a.replay(initial, base, start).Equal(a.current) {
+			return a.current, &CycleError{Start: start, Period: a.Generation - start}
+		} else if !ok {
+			seen[hash] = a.Generation
+		}
+
+		if a.MaxGenerations > 0 && a.Generation >= a.MaxGenerations {
+			return a.current, errors.New(fmt.Sprintf("layout did not stabilise within %d generations", a.MaxGenerations))
+		}
+	}
+
+	return a.current, nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func generateGrid(rows int, seats int, rng *rand.Rand) *Grid {
+	grid := newGrid(rows, seats)
+	for i := range grid.Cells {
+		if rng.Intn(10) == 0 {
+			grid.Cells[i] = Floor
+		}
+	}
+
+	return grid
+}
+
+var namedRules = []struct {
+	name  string
+	rules Rules
+}{
+	{"adjacent", part1Rules},
+	{"line-of-sight", part2Rules},
+}
+
+func TestParallelStepMatchesSequential(t *testing.T) {
+	grid := generateGrid(97, 113, rand.New(rand.NewSource(1)))
+
+	for _, named := range namedRules {
+		sequential := newAutomaton(grid, named.rules)
+		expected := []*Grid{}
+		changes := []int{}
+		for generation := 0; generation < 10; generation++ {
+			changes = append(changes, sequential.Step())
+			expected = append(expected, sequential.Grid().Clone())
+		}
+
+		for _, workers := range []int{2, 3, 8, 200} {
+			parallel := newAutomaton(grid, named.rules)
+			parallel.Workers = workers
+
+			for generation := range expected {
+				if changed := parallel.Step(); changed != changes[generation] {
+					t.Fatalf("%s: %d workers counted %d changes in generation %d, want %d", named.name, workers, changed, parallel.Generation, changes[generation])
+				}
+				if !parallel.Grid().Equal(expected[generation]) {
+					t.Fatalf("%s: %d workers diverged from the sequential result in generation %d", named.name, workers, parallel.Generation)
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkStep(b *testing.B) {
+	for _, size := range []int{1000, 2000} {
+		grid := generateGrid(size, size, rand.New(rand.NewSource(int64(size))))
+
+		for _, named := range namedRules {
+			for _, workers := range []int{1, 2, 4, 8} {
+				name := fmt.Sprintf("%dx%d/%s/workers=%d", size, size, named.name, workers)
+				b.Run(name, func(b *testing.B) {
+					automaton := newAutomaton(grid, named.rules)
+					automaton.Workers = workers
+					b.ResetTimer()
+
+					for i := 0; i < b.N; i++ {
+						automaton.Step()
+					}
+				})
+			}
+		}
+	}
+}
@@ -2,10 +2,12 @@ package main
 
 import (
 	"bufio"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"os"
-	"reflect"
+	"time"
 )
 
 type Direction struct {
@@ -25,8 +27,19 @@ var seatMovement = []Direction{
 }
 
 type Rules struct {
-	ContinueLooking bool
-	SitOrStand      func(occupied int, occupiedAdjacent int) int
+	Neighbourhood Neighbourhood
+	Threshold     int
+	SitOrStand    func(occupied int, occupiedAdjacent int) int
+}
+
+var part1Rules = Rules{
+	Neighbourhood: adjacent,
+	Threshold:     4,
+}
+
+var part2Rules = Rules{
+	Neighbourhood: lineOfSight,
+	Threshold:     5,
 }
 
 func getLayout() (numbers [][]int, err error) {
@@ -65,122 +78,147 @@ func getLayout() (numbers [][]int, err error) {
 	return layout, nil
 }
 
-func occupiedSeats(layout [][]int) int {
-	occ := 0
-	for _, seats := range layout {
-		for _, seat := range seats {
-			if seat > 0 {
-				occ++
-			}
-		}
+var (
+	workers        = 1
+	maxGenerations = 10000
+)
+
+func iterateLayout(layout [][]int, seatingRules Rules) (int, error) {
+	automaton := newAutomaton(gridFromLayout(layout), seatingRules)
+	automaton.Workers = workers
+	automaton.MaxGenerations = maxGenerations
+
+	grid, err := automaton.Run()
+	if err != nil {
+		return 0, err
 	}
 
-	return occ
+	return grid.Occupied(), nil
 }
 
-func iterateLayout(layout [][]int, seatingRules Rules) int {
-	for {
-		newLayout := [][]int{}
-		for row, seats := range layout {
-			newRow := []int{}
-			for seat, _ := range seats {
-				newRow = append(newRow, iterateRow(row, seat, layout, seatingRules))
-			}
-			newLayout = append(newLayout, newRow)
-		}
+func part1(layout [][]int) (int, error) {
+	return iterateLayout(layout, part1Rules)
+}
 
-		if reflect.DeepEqual(layout, newLayout) {
-			break
+func part2(layout [][]int) (int, error) {
+	return iterateLayout(layout, part2Rules)
+}
+
+type visualOptions struct {
+	Terminal bool
+	Delay    time.Duration
+	Dump     string
+	DumpDir  string
+	GIF      string
+	GIFScale int
+}
+
+func visualise(layout [][]int, rules Rules, options visualOptions) error {
+	dumpGen := func(int) bool { return false }
+	if options.Dump != "" {
+		selected, err := parseGenerations(options.Dump)
+		if err != nil {
+			return err
 		}
+		dumpGen = selected
+	}
 
-		layout = newLayout
+	if options.GIF != "" && options.GIFScale < 1 {
+		return errors.New(fmt.Sprintf("GIF scale must be at least 1 pixel per seat, got %d", options.GIFScale))
 	}
 
-	return occupiedSeats(layout)
-}
+	recorder := &gifRecorder{scale: options.GIFScale, delay: int(options.Delay / (10 * time.Millisecond))}
 
-func iterateRow(row int, seat int, layout [][]int, seatingRules Rules) int {
-	if layout[row][seat] == -1 {
-		return -1
-	}
+	var err error
+	automaton := newAutomaton(gridFromLayout(layout), rules)
+	automaton.Workers = workers
+	automaton.MaxGenerations = maxGenerations
+	_, runErr := automaton.RunWith(func(a *Automaton) {
+		if err != nil {
+			return
+		}
 
-	occupiedAdj := 0
-out:
-	for _, adj := range seatMovement {
-		adjRow := row + adj.Row
-		adjSeat := seat + adj.Seat
+		var previous *Grid
+		if a.Generation > 0 {
+			previous = a.Previous()
+		}
 
-		for {
-			if adjRow < 0 || adjRow >= len(layout) || adjSeat < 0 || adjSeat >= len(layout[row]) {
-				continue out
+		if options.Terminal {
+			if err = renderANSI(os.Stdout, a.Grid(), previous, a.Generation); err != nil {
+				return
 			}
+			time.Sleep(options.Delay)
+		}
 
-			if layout[adjRow][adjSeat] != -1 || !seatingRules.ContinueLooking {
-				break
+		if dumpGen(a.Generation) {
+			if err = dumpGeneration(options.DumpDir, a.Grid(), a.Generation); err != nil {
+				return
 			}
-
-			adjRow = adjRow + adj.Row
-			adjSeat = adjSeat + adj.Seat
 		}
 
-		if layout[adjRow][adjSeat] > 0 {
-			occupiedAdj++
+		if options.GIF != "" {
+			recorder.add(a.Grid())
 		}
+	})
+	if err != nil {
+		return err
 	}
 
-	return seatingRules.SitOrStand(layout[row][seat], occupiedAdj)
-}
-
-func sitOrStandPart1(occupied int, occupiedAdj int) int {
-	switch occupied {
-	case 0:
-		if occupiedAdj == 0 {
-			return 1
-		}
-	case 1:
-		if occupiedAdj >= 4 {
-			return 0
+	if options.GIF != "" {
+		if err := recorder.save(options.GIF); err != nil {
+			return err
 		}
 	}
 
-	return occupied
+	return runErr
 }
 
-func sitOrStandPart2(occupied int, occupiedAdj int) int {
-	switch occupied {
-	case 0:
-		if occupiedAdj == 0 {
-			return 1
+func main() {
+	flag.IntVar(&workers, "workers", 1, "number of goroutines each generation is split across")
+	flag.IntVar(&maxGenerations, "max-generations", 10000, "give up if the layout has not stabilised after this many generations (0 for no limit)")
+	animate := flag.Int("animate", 0, "animate this part's simulation in the terminal, or only record it when -dump or -gif is given")
+	delay := flag.Duration("delay", 200*time.Millisecond, "pause between animated generations")
+	dump := flag.String("dump", "", "comma-separated generations (or all) of -animate's part to write to -dump-dir")
+	dumpDir := flag.String("dump-dir", ".", "directory generation dumps are written to")
+	gifPath := flag.String("gif", "", "write -animate's part as an animated GIF to this file")
+	gifScale := flag.Int("gif-scale", 4, "pixels per seat in the GIF")
+	flag.Parse()
+
+	layout, err := getLayout()
+	if err != nil {
+		log.Fatalln(fmt.Sprintf("Error fetching layout: %s", err))
+	}
+
+	if *animate != 0 {
+		rules := part1Rules
+		if *animate == 2 {
+			rules = part2Rules
 		}
-	case 1:
-		if occupiedAdj >= 5 {
-			return 0
+
+		err := visualise(layout, rules, visualOptions{
+			Terminal: *dump == "" && *gifPath == "",
+			Delay:    *delay,
+			Dump:     *dump,
+			DumpDir:  *dumpDir,
+			GIF:      *gifPath,
+			GIFScale: *gifScale,
+		})
+		if err != nil {
+			log.Fatalln(fmt.Sprintf("Error visualising part %d: %s", *animate, err))
 		}
 	}
 
-	return occupied
-}
-
-func part1(layout [][]int) int {
-	return iterateLayout(layout, Rules{
-		ContinueLooking: false,
-		SitOrStand:      sitOrStandPart1,
-	})
-}
+	occupied, err := part1(layout)
+	if err != nil {
+		log.Fatalln(fmt.Sprintf("Error running part 1: %s", err))
+	}
 
-func part2(layout [][]int) int {
-	return iterateLayout(layout, Rules{
-		ContinueLooking: true,
-		SitOrStand:      sitOrStandPart2,
-	})
-}
+	log.Println(fmt.Sprintf("There were %d seats occupied in part 1", occupied))
 
-func main() {
-	layout, err := getLayout()
+	occupied, err = part2(layout)
 	if err != nil {
-		log.Fatalln(fmt.Sprintf("Error fetching layout: %s", err))
+		log.Fatalln(fmt.Sprintf("Error running part 2: %s", err))
 	}
 
-	log.Println(fmt.Sprintf("There were %d seats occupied in part 1", part1(layout)))
-	log.Println(fmt.Sprintf("There were %d seats occupied in part 2", part2(layout)))
+	log.Println(fmt.Sprintf("There were %d seats occupied in part 2", occupied))
 }
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	ansiReset = "\033[0m"
+	ansiSat   = "\033[1;31m"
+	ansiStood = "\033[1;32m"
+	ansiHome  = "\033[H\033[2J"
+)
+
+var glyphs = map[int8]byte{
+	Floor:    '.',
+	Empty:    'L',
+	Occupied: '#',
+}
+
+var palette = color.Palette{
+	color.RGBA{R: 0x20, G: 0x20, B: 0x20, A: 0xff},
+	color.RGBA{R: 0x5f, G: 0xaf, B: 0x5f, A: 0xff},
+	color.RGBA{R: 0xd7, G: 0x5f, B: 0x5f, A: 0xff},
+}
+
+var paletteIndex = map[int8]uint8{
+	Floor:    0,
+	Empty:    1,
+	Occupied: 2,
+}
+
+func (g *Grid) String() string {
+	var sb strings.Builder
+	for row := 0; row < g.Rows; row++ {
+		for seat := 0; seat < g.Seats; seat++ {
+			sb.WriteByte(glyphs[g.At(row, seat)])
+		}
+		sb.WriteByte('\n')
+	}
+
+	return sb.String()
+}
+
+func renderANSI(w io.Writer, current *Grid, previous *Grid, generation int) error {
+	var sb strings.Builder
+	sb.WriteString(ansiHome)
+	sb.WriteString(fmt.Sprintf("generation %d\n", generation))
+
+	for row := 0; row < current.Rows; row++ {
+		for seat := 0; seat < current.Seats; seat++ {
+			cell := current.At(row, seat)
+			if previous == nil || previous.At(row, seat) == cell {
+				sb.WriteByte(glyphs[cell])
+				continue
+			}
+
+			colour := ansiStood
+			if cell == Occupied {
+				colour = ansiSat
+			}
+			sb.WriteString(colour)
+			sb.WriteByte(glyphs[cell])
+			sb.WriteString(ansiReset)
+		}
+		sb.WriteByte('\n')
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func parseGenerations(input string) (func(generation int) bool, error) {
+	if input == "all" {
+		return func(int) bool { return true }, nil
+	}
+
+	selected := map[int]bool{}
+	for _, field := range strings.Split(input, ",") {
+		generation, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || generation < 0 {
+			return nil, errors.New(fmt.Sprintf("invalid generation %q", field))
+		}
+		selected[generation] = true
+	}
+
+	return func(generation int) bool { return selected[generation] }, nil
+}
+
+func dumpGeneration(dir string, grid *Grid, generation int) error {
+	path := filepath.Join(dir, fmt.Sprintf("generation-%04d.txt", generation))
+	return ioutil.WriteFile(path, []byte(grid.String()), 0644)
+}
+
+type gifRecorder struct {
+	scale int
+	delay int
+	anim  gif.GIF
+}
+
+func (r *gifRecorder) add(grid *Grid) {
+	frame := image.NewPaletted(image.Rect(0, 0, grid.Seats*r.scale, grid.Rows*r.scale), palette)
+	for y := 0; y < grid.Rows*r.scale; y++ {
+		for x := 0; x < grid.Seats*r.scale; x++ {
+			frame.SetColorIndex(x, y, paletteIndex[grid.At(y/r.scale, x/r.scale)])
+		}
+	}
+
+	r.anim.Image = append(r.anim.Image, frame)
+	r.anim.Delay = append(r.anim.Delay, r.delay)
+}
+
+func (r *gifRecorder) save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return gif.EncodeAll(f, &r.anim)
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"unicode"
+)
+
+const (
+	maxCompiledDirections = 10000000
+	resetInstruction      = "reset"
+)
+
+var instructionRe = regexp.MustCompile(`^([A-Z])(\d+)$`)
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenNumber
+	tokenOpen
+	tokenClose
+	tokenEOF
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	line int
+	col  int
+}
+
+type SyntaxError struct {
+	Line    int
+	Col     int
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Col, e.Message)
+}
+
+// positionError puts the source position of a compiled direction in front of
+// an error raised while running it. Directions built outside the language
+// have no position and their errors are returned as they are.
+func (d *Direction) positionError(err error) error {
+	if d.Line == 0 {
+		return err
+	}
+
+	return errors.New(fmt.Sprintf("%d:%d: %s", d.Line, d.Col, err))
+}
+
+func lex(source string) ([]token, error) {
+	tokens := []token{}
+	runes := []rune(source)
+	line, col := 1, 1
+
+	advance := func() rune {
+		r := runes[0]
+		runes = runes[1:]
+		if r == '\n' {
+			line++
+			col = 1
+		} else {
+			col++
+		}
+		return r
+	}
+
+	for len(runes) > 0 {
+		r := runes[0]
+		start := token{line: line, col: col}
+
+		switch {
+		case unicode.IsSpace(r) || r == ',':
+			advance()
+		case r == '#' || r == ';':
+			for len(runes) > 0 && runes[0] != '\n' {
+				advance()
+			}
+		case r == '{':
+			advance()
+			start.kind, start.text = tokenOpen, "{"
+			tokens = append(tokens, start)
+		case r == '}':
+			advance()
+			start.kind, start.text = tokenClose, "}"
+			tokens = append(tokens, start)
+		case unicode.IsDigit(r):
+			for len(runes) > 0 && unicode.IsDigit(runes[0]) {
+				start.text += string(advance())
+			}
+			start.kind = tokenNumber
+			tokens = append(tokens, start)
+		case unicode.IsLetter(r) || r == '_':
+			for len(runes) > 0 && (unicode.IsLetter(runes[0]) || unicode.IsDigit(runes[0]) || runes[0] == '_') {
+				start.text += string(advance())
+			}
+			start.kind = tokenWord
+			tokens = append(tokens, start)
+		default:
+			return nil, &SyntaxError{Line: line, Col: col, Message: fmt.Sprintf("unexpected character %q", r)}
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, line: line, col: col}), nil
+}
+
+type compiler struct {
+	tokens []token
+	pos    int
+	macros map[string][]*Direction
+}
+
+func compileDirections(source string) ([]*Direction, error) {
+	tokens, err := lex(source)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &compiler{
+		tokens: tokens,
+		macros: map[string][]*Direction{},
+	}
+
+	directions, err := c.block(false)
+	if err != nil {
+		return nil, err
+	}
+
+	return directions, nil
+}
+
+func (c *compiler) peek() token {
+	return c.tokens[c.pos]
+}
+
+func (c *compiler) next() token {
+	t := c.tokens[c.pos]
+	if t.kind != tokenEOF {
+		c.pos++
+	}
+	return t
+}
+
+func (c *compiler) fail(t token, format string, args ...interface{}) error {
+	return &SyntaxError{Line: t.line, Col: t.col, Message: fmt.Sprintf(format, args...)}
+}
+
+func (c *compiler) block(nested bool) ([]*Direction, error) {
+	directions := []*Direction{}
+	for {
+		t := c.peek()
+		switch t.kind {
+		case tokenEOF:
+			if nested {
+				return nil, c.fail(t, "unexpected end of input, expected }")
+			}
+			return directions, nil
+		case tokenClose:
+			if !nested {
+				return nil, c.fail(t, "unexpected }")
+			}
+			c.next()
+			return directions, nil
+		}
+
+		compiled, err := c.statement()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(directions)+len(compiled) > maxCompiledDirections {
+			return nil, c.fail(t, "program expands to more than %d directions", maxCompiledDirections)
+		}
+		directions = append(directions, compiled...)
+	}
+}
+
+func (c *compiler) statement() ([]*Direction, error) {
+	t := c.next()
+	switch t.kind {
+	case tokenNumber:
+		return c.repeat(t)
+	case tokenWord:
+	default:
+		return nil, c.fail(t, "unexpected %q", t.text)
+	}
+
+	if matches := instructionRe.FindStringSubmatch(t.text); matches != nil {
+		amount, err := strconv.Atoi(matches[2])
+		if err != nil {
+			return nil, c.fail(t, "invalid amount in %s", t.text)
+		}
+		return []*Direction{{Instruction: matches[1], Amount: amount, Line: t.line, Col: t.col}}, nil
+	}
+
+	switch t.text {
+	case "reset":
+		return []*Direction{{Instruction: resetInstruction, Line: t.line, Col: t.col}}, nil
+	case "def":
+		return nil, c.define()
+	}
+
+	body, ok := c.macros[t.text]
+	if !ok {
+		return nil, c.fail(t, "unknown instruction or macro %q", t.text)
+	}
+
+	return body, nil
+}
+
+func (c *compiler) repeat(count token) ([]*Direction, error) {
+	times, err := strconv.Atoi(count.text)
+	if err != nil {
+		return nil, c.fail(count, "invalid repeat count %s", count.text)
+	}
+
+	if x := c.next(); x.kind != tokenWord || x.text != "x" {
+		return nil, c.fail(x, "expected x after repeat count %d", times)
+	}
+	if open := c.next(); open.kind != tokenOpen {
+		return nil, c.fail(open, "expected { to start repeat block")
+	}
+
+	body, err := c.block(true)
+	if err != nil {
+		return nil, err
+	}
+
+	if times > maxCompiledDirections || (len(body) > 0 && times > maxCompiledDirections/len(body)) {
+		return nil, c.fail(count, "repeat expands to more than %d directions", maxCompiledDirections)
+	}
+
+	directions := make([]*Direction, 0, times*len(body))
+	for i := 0; i < times; i++ {
+		directions = append(directions, body...)
+	}
+
+	return directions, nil
+}
+
+func (c *compiler) define() error {
+	name := c.next()
+	if name.kind != tokenWord {
+		return c.fail(name, "expected macro name after def")
+	}
+	if instructionRe.MatchString(name.text) || name.text == "reset" || name.text == "def" || name.text == "x" {
+		return c.fail(name, "%q is reserved and cannot be used as a macro name", name.text)
+	}
+	if _, ok := c.macros[name.text]; ok {
+		return c.fail(name, "macro %q is already defined", name.text)
+	}
+
+	if open := c.next(); open.kind != tokenOpen {
+		return c.fail(open, "expected { to start macro %s", name.text)
+	}
+
+	body, err := c.block(true)
+	if err != nil {
+		return err
+	}
+
+	c.macros[name.text] = body
+	return nil
+}
+
+// WaypointResetter is implemented by nav methods with a waypoint that the
+// reset instruction can put back where it started. For any other method reset
+// does nothing.
+type WaypointResetter interface {
+	ResetWaypoint()
+}
+
+func (w *WaypointNav) ResetWaypoint() {
+	*w.Waypoint = w.Start
+	if w.waypoint != nil {
+		w.waypoint.posX = float64(w.Start.posX)
+		w.waypoint.posY = float64(w.Start.posY)
+	}
+}
+
+func (d *DriftNav) ResetWaypoint() {
+	if resetter, ok := d.NavMethod.(WaypointResetter); ok {
+		resetter.ResetWaypoint()
+	}
+}
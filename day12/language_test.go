@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestRunErrorsCarryPosition(t *testing.T) {
+	cases := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{name: "unknown letter", source: "F10\n  G10", want: "2:3: unexpected instruction: G"},
+		{name: "Z is not reset", source: "N1 Z5", want: "1:4: unexpected instruction: Z"},
+		{name: "inside a macro", source: "def bad {\n\tQ1\n}\n2 x { bad }", want: "2:2: unexpected instruction: Q"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			directions, err := compileDirections(c.source)
+			if err != nil {
+				t.Fatalf("compiling %q: %s", c.source, err)
+			}
+
+			_, err = part2(directions)
+			if err == nil || err.Error() != c.want {
+				t.Errorf("got error %v, want %q", err, c.want)
+			}
+		})
+	}
+}
+
+func TestResetCompilesToSentinel(t *testing.T) {
+	directions, err := compileDirections("N4 reset F1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := directions[1].Instruction; got != resetInstruction {
+		t.Fatalf("reset compiled to %q", got)
+	}
+	if distance, err := part2(directions); err != nil || distance != 11 {
+		t.Errorf("got distance %d and error %v, want 11", distance, err)
+	}
+}
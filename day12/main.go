@@ -1,126 +1,245 @@
 package main
 
 import (
-	"bufio"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
 	"math"
 	"os"
-	"regexp"
-	"strconv"
+	"strings"
 )
 
 type Coords struct {
 	posX int
 	posY int
+	posZ int
 }
 
 type Direction struct {
 	Instruction string
 	Amount      int
+	Line        int
+	Col         int
 }
 
 type Navigator struct {
 	Directions []*Direction
 	Ship       *Coords
 	NavMethod  NavMethod
+	Track      []TrackPoint
 }
 
 func (n *Navigator) Run() (int, error) {
-	for _, direction := range n.Directions {
-		switch direction.Instruction {
-		case "N":
-			fallthrough
-		case "E":
-			fallthrough
-		case "S":
-			fallthrough
-		case "W":
-			n.NavMethod.Move(direction, n.Ship)
-		case "R":
-			fallthrough
-		case "L":
-			n.NavMethod.Turn(direction, n.Ship)
-		case "F":
-			n.NavMethod.Advance(direction, n.Ship)
-		default:
-			return 0, errors.New(fmt.Sprintf("unexpected instruction: %s", direction.Instruction))
+	n.Track = nil
+	n.record(0, nil)
+
+	for step, direction := range n.Directions {
+		if err := n.apply(direction); err != nil {
+			return 0, direction.positionError(err)
+		}
+
+		n.record(step+1, direction)
+	}
+
+	if measurer, ok := n.NavMethod.(DistanceMeasurer); ok {
+		return measurer.Distance(n.Ship), nil
+	}
+
+	return abs(n.Ship.posX) + abs(n.Ship.posY) + abs(n.Ship.posZ), nil
+}
+
+func (n *Navigator) apply(direction *Direction) error {
+	switch direction.Instruction {
+	case "N":
+		fallthrough
+	case "E":
+		fallthrough
+	case "S":
+		fallthrough
+	case "W":
+		return n.NavMethod.Move(direction, n.Ship)
+	case "R":
+		fallthrough
+	case "L":
+		return n.NavMethod.Turn(direction, n.Ship)
+	case "F":
+		return n.NavMethod.Advance(direction, n.Ship)
+	case resetInstruction:
+		if resetter, ok := n.NavMethod.(WaypointResetter); ok {
+			resetter.ResetWaypoint()
+		}
+		return nil
+	}
+
+	if handler, ok := n.NavMethod.(InstructionHandler); ok {
+		handled, err := handler.Handle(direction, n.Ship)
+		if err != nil || handled {
+			return err
 		}
 	}
 
-	return int(math.Abs(float64(n.Ship.posX))) + int(math.Abs(float64(n.Ship.posY))), nil
+	return errors.New(fmt.Sprintf("unexpected instruction: %s", direction.Instruction))
 }
 
 type NavMethod interface {
-	Move(dir *Direction, ship *Coords)
-	Turn(dir *Direction, ship *Coords)
-	Advance(dir *Direction, ship *Coords)
+	Move(dir *Direction, ship *Coords) error
+	Turn(dir *Direction, ship *Coords) error
+	Advance(dir *Direction, ship *Coords) error
+}
+
+type realCoords struct {
+	posX float64
+	posY float64
+}
+
+func (r *realCoords) round(obj *Coords) {
+	obj.posX = int(math.Round(r.posX))
+	obj.posY = int(math.Round(r.posY))
 }
 
 type HeadingNav struct {
-	Heading int
+	Heading    int
+	RealValued bool
+	ship       *realCoords
 }
 
-func (h *HeadingNav) Move(dir *Direction, ship *Coords) {
+func (h *HeadingNav) Move(dir *Direction, ship *Coords) error {
+	if h.RealValued {
+		h.realShip(ship)
+		x, y := unitVector(dir.Instruction)
+		h.ship.posX += x * float64(dir.Amount)
+		h.ship.posY += y * float64(dir.Amount)
+		h.ship.round(ship)
+		return nil
+	}
+
 	moveObj(ship, dir)
+	return nil
 }
 
-func (h *HeadingNav) Turn(dir *Direction, ship *Coords) {
+func (h *HeadingNav) Turn(dir *Direction, ship *Coords) error {
 	switch dir.Instruction {
 	case "R":
-		h.Heading = (h.Heading + dir.Amount) % 360
+		h.Heading = normaliseAngle(h.Heading + dir.Amount)
 	case "L":
-		if h.Heading < dir.Amount {
-			h.Heading = (h.Heading + 360) - dir.Amount
-		} else {
-			h.Heading -= dir.Amount
-		}
+		h.Heading = normaliseAngle(h.Heading - dir.Amount)
 	}
+
+	return nil
 }
 
-func (h *HeadingNav) Advance(dir *Direction, ship *Coords) {
-	if h.Heading < 90 {
+func (h *HeadingNav) Advance(dir *Direction, ship *Coords) error {
+	if h.RealValued {
+		h.realShip(ship)
+		angle := degToRad(float64(h.Heading))
+		h.ship.posX += math.Sin(angle) * float64(dir.Amount)
+		h.ship.posY += math.Cos(angle) * float64(dir.Amount)
+		h.ship.round(ship)
+		return nil
+	}
+
+	switch h.Heading {
+	case 0:
 		ship.posY += dir.Amount
-	} else if h.Heading < 180 {
+	case 90:
 		ship.posX += dir.Amount
-	} else if h.Heading < 270 {
+	case 180:
 		ship.posY -= dir.Amount
-	} else if h.Heading < 360 {
+	case 270:
 		ship.posX -= dir.Amount
+	default:
+		return errors.New(fmt.Sprintf("cannot advance on non-cardinal heading %d without real-valued navigation", h.Heading))
+	}
+
+	return nil
+}
+
+func (h *HeadingNav) realShip(ship *Coords) {
+	if h.ship == nil {
+		h.ship = &realCoords{posX: float64(ship.posX), posY: float64(ship.posY)}
 	}
 }
 
 type WaypointNav struct {
-	Waypoint *Coords
+	Waypoint   *Coords
+	Start      Coords
+	RealValued bool
+	ship       *realCoords
+	waypoint   *realCoords
 }
 
-func (w *WaypointNav) Move(dir *Direction, ship *Coords) {
+func (w *WaypointNav) Move(dir *Direction, ship *Coords) error {
+	if w.RealValued {
+		w.realState(ship)
+		x, y := unitVector(dir.Instruction)
+		w.waypoint.posX += x * float64(dir.Amount)
+		w.waypoint.posY += y * float64(dir.Amount)
+		w.waypoint.round(w.Waypoint)
+		return nil
+	}
+
 	moveObj(w.Waypoint, dir)
+	return nil
 }
 
-func (w *WaypointNav) Turn(dir *Direction, ship *Coords) {
-	switch dir.Instruction {
-	case "R":
-		angle := degToRad(float64(dir.Amount))
-		w.RotateWaypoint(angle)
-	case "L":
-		angle := degToRad(float64(dir.Amount))
-		w.RotateWaypoint(angle * -1)
+func (w *WaypointNav) Turn(dir *Direction, ship *Coords) error {
+	degrees := dir.Amount
+	if dir.Instruction == "L" {
+		degrees = -degrees
+	}
+
+	if w.RealValued {
+		w.realState(ship)
+		w.rotateReal(degToRad(float64(degrees)))
+		return nil
 	}
+
+	return w.RotateWaypoint(degrees)
 }
 
-func (w *WaypointNav) Advance(dir *Direction, ship *Coords) {
+func (w *WaypointNav) Advance(dir *Direction, ship *Coords) error {
+	if w.RealValued {
+		w.realState(ship)
+		w.ship.posX += w.waypoint.posX * float64(dir.Amount)
+		w.ship.posY += w.waypoint.posY * float64(dir.Amount)
+		w.ship.round(ship)
+		return nil
+	}
+
 	ship.posY += w.Waypoint.posY * dir.Amount
 	ship.posX += w.Waypoint.posX * dir.Amount
+	return nil
 }
 
-func (w *WaypointNav) RotateWaypoint(angle float64) {
-	x := int(float64(w.Waypoint.posX)*math.Cos(angle)) + int(float64(w.Waypoint.posY)*math.Sin(angle))
-	y := int(float64(w.Waypoint.posY)*math.Cos(angle)) - int(float64(w.Waypoint.posX)*math.Sin(angle))
+func (w *WaypointNav) RotateWaypoint(degrees int) error {
+	if degrees%90 != 0 {
+		return errors.New(fmt.Sprintf("cannot rotate waypoint by %d degrees, only multiples of 90 are exact", degrees))
+	}
 
-	w.Waypoint.posX = x
-	w.Waypoint.posY = y
+	for quarters := normaliseAngle(degrees) / 90; quarters > 0; quarters-- {
+		w.Waypoint.posX, w.Waypoint.posY = w.Waypoint.posY, -w.Waypoint.posX
+	}
+
+	return nil
+}
+
+func (w *WaypointNav) rotateReal(angle float64) {
+	x := w.waypoint.posX*math.Cos(angle) + w.waypoint.posY*math.Sin(angle)
+	y := w.waypoint.posY*math.Cos(angle) - w.waypoint.posX*math.Sin(angle)
+
+	w.waypoint.posX = x
+	w.waypoint.posY = y
+	w.waypoint.round(w.Waypoint)
+}
+
+func (w *WaypointNav) realState(ship *Coords) {
+	if w.ship == nil {
+		w.ship = &realCoords{posX: float64(ship.posX), posY: float64(ship.posY)}
+		w.waypoint = &realCoords{posX: float64(w.Waypoint.posX), posY: float64(w.Waypoint.posY)}
+	}
 }
 
 func moveObj(obj *Coords, dir *Direction) {
@@ -136,77 +255,58 @@ func moveObj(obj *Coords, dir *Direction) {
 	}
 }
 
-func degToRad(deg float64) float64 {
-	return deg * (math.Pi / 180)
-}
-
-func getDirections() ([]*Direction, error) {
-	f, err := os.Open("input.txt")
-	if err != nil {
-		return nil, err
+func unitVector(instruction string) (float64, float64) {
+	switch instruction {
+	case "N":
+		return 0, 1
+	case "E":
+		return 1, 0
+	case "S":
+		return 0, -1
+	case "W":
+		return -1, 0
 	}
-	defer f.Close()
-
-	scanner := bufio.NewScanner(f)
-	var directions []*Direction
 
-	for scanner.Scan() {
-		err := scanner.Err()
-		if err != nil {
-			return nil, err
-		}
-
-		dir, err := getDirection(scanner.Text())
-		if err != nil {
-			return nil, err
-		}
+	return 0, 0
+}
 
-		directions = append(directions, dir)
-	}
+func normaliseAngle(degrees int) int {
+	return ((degrees % 360) + 360) % 360
+}
 
-	return directions, nil
+func degToRad(deg float64) float64 {
+	return deg * (math.Pi / 180)
 }
 
-func getDirection(input string) (*Direction, error) {
-	re := regexp.MustCompile(`^(\w{1})(\d+)$`)
-	matches := re.FindStringSubmatch(input)
-	if len(matches) < 3 {
-		return nil, errors.New(fmt.Sprintf("unexpected format for line: %s", input))
-	}
+var inputPath = "input.txt"
 
-	amount, err := strconv.Atoi(matches[2])
+func getDirections() ([]*Direction, error) {
+	source, err := ioutil.ReadFile(inputPath)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Direction{
-		Instruction: matches[1],
-		Amount:      amount,
-	}, nil
+	return compileDirections(string(source))
 }
 
-func part1(directions []*Direction) (int, error) {
-	nav := &Navigator{
+var realValued = false
+
+func part1Navigator(directions []*Direction) *Navigator {
+	return &Navigator{
 		Directions: directions,
 		Ship: &Coords{
 			posX: 0,
 			posY: 0,
 		},
 		NavMethod: &HeadingNav{
-			Heading: 90,
+			Heading:    90,
+			RealValued: realValued,
 		},
 	}
-
-	mDistance, err := nav.Run()
-	if err != nil {
-		return 0, err
-	}
-
-	return mDistance, nil
 }
 
-func part2(directions []*Direction) (int, error) {
-	nav := &Navigator{
+func part2Navigator(directions []*Direction) *Navigator {
+	return &Navigator{
 		Directions: directions,
 		Ship: &Coords{
 			posX: 0,
@@ -217,10 +317,17 @@ func part2(directions []*Direction) (int, error) {
 				posX: 10,
 				posY: 1,
 			},
+			Start: Coords{
+				posX: 10,
+				posY: 1,
+			},
+			RealValued: realValued,
 		},
 	}
+}
 
-	mDistance, err := nav.Run()
+func part1(directions []*Direction) (int, error) {
+	mDistance, err := part1Navigator(directions).Run()
 	if err != nil {
 		return 0, err
 	}
@@ -228,12 +335,119 @@ func part2(directions []*Direction) (int, error) {
 	return mDistance, nil
 }
 
+func part2(directions []*Direction) (int, error) {
+	mDistance, err := part2Navigator(directions).Run()
+	if err != nil {
+		return 0, err
+	}
+
+	return mDistance, nil
+}
+
+func exportTrack(nav *Navigator, csvPath string, svgPath string, pngPath string, pngSize int) error {
+	if _, err := nav.Run(); err != nil {
+		return err
+	}
+
+	exports := []struct {
+		path  string
+		write func(w io.Writer) error
+	}{
+		{csvPath, func(w io.Writer) error { return writeTrackCSV(w, nav.Track) }},
+		{svgPath, func(w io.Writer) error { return writeTrackSVG(w, nav.Track) }},
+		{pngPath, func(w io.Writer) error { return writeTrackPNG(w, nav.Track, pngSize) }},
+	}
+
+	for _, export := range exports {
+		if export.path == "" {
+			continue
+		}
+
+		f, err := os.Create(export.path)
+		if err != nil {
+			return err
+		}
+
+		err = export.write(f)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func main() {
+	flag.StringVar(&inputPath, "input", inputPath, "file to read directions from")
+	flag.BoolVar(&realValued, "real", false, "track positions as floats so turns need not be multiples of 90 degrees")
+	trackPart := flag.Int("track", 0, "export the ship's track for this part")
+	csvPath := flag.String("csv", "", "write the track as CSV to this file")
+	svgPath := flag.String("svg", "", "write the track as an SVG polyline to this file")
+	pngPath := flag.String("png", "", "write the track as a PNG image to this file")
+	pngSize := flag.Int("png-size", 800, "longest side of the PNG image in pixels")
+	method := flag.String("method", "", fmt.Sprintf("run the directions with this nav method (%s)", strings.Join(navMethodNames(), ", ")))
+	flag.IntVar(&driftCurrent.posX, "drift-x", driftCurrent.posX, "east offset applied per step by the drift method")
+	flag.IntVar(&driftCurrent.posY, "drift-y", driftCurrent.posY, "north offset applied per step by the drift method")
+	route := flag.String("route", "", "find the shortest instruction sequence from the origin to x,y[,z]")
+	routeQuery := RouteQuery{}
+	flag.StringVar(&routeQuery.Method, "route-method", "heading", "nav method used by -route")
+	flag.StringVar(&routeQuery.Instructions, "route-instructions", "NESWLRF", "instructions -route may use")
+	flag.IntVar(&routeQuery.Step, "route-step", 1, "granularity of instruction amounts used by -route")
+	flag.IntVar(&routeQuery.MaxAmount, "route-max", 10, "largest instruction amount used by -route")
+	flag.IntVar(&routeQuery.TurnStep, "route-turn", 90, "granularity of turns used by -route")
+	flag.IntVar(&routeQuery.MaxStates, "route-states", 1000000, "give up -route after visiting this many states")
+	flag.Parse()
+
+	if *route != "" {
+		target, err := parseCoords(*route)
+		if err != nil {
+			log.Fatalln(fmt.Sprintf("Error reading route target: %s", err))
+		}
+		routeQuery.Target = target
+
+		directions, err := findRoute(routeQuery)
+		if err != nil {
+			log.Fatalln(fmt.Sprintf("Error finding route: %s", err))
+		}
+
+		log.Println(fmt.Sprintf("Shortest route to %s takes %d instructions: %s", *route, len(directions), formatRoute(directions)))
+		return
+	}
+
 	directions, err := getDirections()
 	if err != nil {
 		log.Fatalln(fmt.Sprintf("Error fetching layout: %s", err))
 	}
 
+	if *method != "" {
+		navMethod, err := newNavMethod(*method)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		mDistance, err := (&Navigator{Directions: directions, Ship: &Coords{}, NavMethod: navMethod}).Run()
+		if err != nil {
+			log.Fatalln(fmt.Sprintf("Error navigating with %s: %s", *method, err))
+		}
+
+		log.Println(fmt.Sprintf("The distance is %d using the %s nav method", mDistance, *method))
+		return
+	}
+
+	if *trackPart != 0 {
+		nav := part1Navigator(directions)
+		if *trackPart == 2 {
+			nav = part2Navigator(directions)
+		}
+
+		if err := exportTrack(nav, *csvPath, *svgPath, *pngPath, *pngSize); err != nil {
+			log.Fatalln(fmt.Sprintf("Error exporting track for part %d: %s", *trackPart, err))
+		}
+	}
+
 	mDistance, err := part1(directions)
 	if err != nil {
 		log.Fatalln(fmt.Sprintf("Error calculating Manhattan distance for part 1: %s", err))
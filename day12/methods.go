@@ -0,0 +1,199 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type InstructionHandler interface {
+	Handle(dir *Direction, ship *Coords) (bool, error)
+}
+
+type DistanceMeasurer interface {
+	Distance(ship *Coords) int
+}
+
+var hexSteps = map[int]Coords{
+	0:   {posX: 0, posY: 1},
+	60:  {posX: 1, posY: 0},
+	120: {posX: 1, posY: -1},
+	180: {posX: 0, posY: -1},
+	240: {posX: -1, posY: 0},
+	300: {posX: -1, posY: 1},
+}
+
+// HexNav sails a flat-topped hex grid in axial coordinates, so its heading is
+// always one of the six sides, a multiple of 60 degrees. A flat-topped hex has
+// no east or west neighbour, so the E and W moves go along the x axis, which
+// runs north-east to south-west.
+type HexNav struct {
+	Heading int
+}
+
+var hexMoves = map[string]int{
+	"N": 0,
+	"E": 60,
+	"S": 180,
+	"W": 240,
+}
+
+func (h *HexNav) Move(dir *Direction, ship *Coords) error {
+	heading, ok := hexMoves[dir.Instruction]
+	if !ok {
+		return errors.New(fmt.Sprintf("unexpected hex move: %s", dir.Instruction))
+	}
+
+	return hexStep(ship, heading, dir.Amount)
+}
+
+func (h *HexNav) Turn(dir *Direction, ship *Coords) error {
+	if dir.Amount%60 != 0 {
+		return errors.New(fmt.Sprintf("cannot turn %d degrees on a hex grid, only multiples of 60", dir.Amount))
+	}
+
+	switch dir.Instruction {
+	case "R":
+		h.Heading = normaliseAngle(h.Heading + dir.Amount)
+	case "L":
+		h.Heading = normaliseAngle(h.Heading - dir.Amount)
+	}
+
+	return nil
+}
+
+func (h *HexNav) Advance(dir *Direction, ship *Coords) error {
+	return hexStep(ship, h.Heading, dir.Amount)
+}
+
+func (h *HexNav) Distance(ship *Coords) int {
+	return (abs(ship.posX) + abs(ship.posY) + abs(ship.posX+ship.posY)) / 2
+}
+
+func hexStep(ship *Coords, heading int, amount int) error {
+	step, ok := hexSteps[heading]
+	if !ok {
+		return errors.New(fmt.Sprintf("heading %d is not a hex direction", heading))
+	}
+
+	ship.posX += step.posX * amount
+	ship.posY += step.posY * amount
+	return nil
+}
+
+type Nav3D struct {
+	HeadingNav
+}
+
+func (n *Nav3D) Handle(dir *Direction, ship *Coords) (bool, error) {
+	switch dir.Instruction {
+	case "U":
+		ship.posZ += dir.Amount
+	case "D":
+		ship.posZ -= dir.Amount
+	default:
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// ShipShifter is implemented by nav methods that keep their own copy of the
+// ship's position, so an offset applied from outside has to reach it as well
+// as the integer position.
+type ShipShifter interface {
+	ShiftShip(offset Coords)
+}
+
+func (h *HeadingNav) ShiftShip(offset Coords) {
+	if h.ship != nil {
+		h.ship.posX += float64(offset.posX)
+		h.ship.posY += float64(offset.posY)
+	}
+}
+
+func (w *WaypointNav) ShiftShip(offset Coords) {
+	if w.ship != nil {
+		w.ship.posX += float64(offset.posX)
+		w.ship.posY += float64(offset.posY)
+	}
+}
+
+type DriftNav struct {
+	NavMethod NavMethod
+	Current   Coords
+}
+
+func (d *DriftNav) Move(dir *Direction, ship *Coords) error {
+	return d.drift(ship, d.NavMethod.Move(dir, ship))
+}
+
+func (d *DriftNav) Turn(dir *Direction, ship *Coords) error {
+	return d.drift(ship, d.NavMethod.Turn(dir, ship))
+}
+
+func (d *DriftNav) Advance(dir *Direction, ship *Coords) error {
+	return d.drift(ship, d.NavMethod.Advance(dir, ship))
+}
+
+func (d *DriftNav) drift(ship *Coords, err error) error {
+	if err != nil {
+		return err
+	}
+
+	ship.posX += d.Current.posX
+	ship.posY += d.Current.posY
+	ship.posZ += d.Current.posZ
+	if shifter, ok := d.NavMethod.(ShipShifter); ok {
+		shifter.ShiftShip(d.Current)
+	}
+	return nil
+}
+
+func (d *DriftNav) WaypointPosition() (Coords, bool) {
+	if holder, ok := d.NavMethod.(WaypointHolder); ok {
+		return holder.WaypointPosition()
+	}
+
+	return Coords{}, false
+}
+
+var navMethods = map[string]func() NavMethod{
+	"heading": func() NavMethod {
+		return &HeadingNav{Heading: 90, RealValued: realValued}
+	},
+	"waypoint": func() NavMethod {
+		return &WaypointNav{Waypoint: &Coords{posX: 10, posY: 1}, Start: Coords{posX: 10, posY: 1}, RealValued: realValued}
+	},
+	"hex": func() NavMethod {
+		return &HexNav{Heading: 60}
+	},
+	"3d": func() NavMethod {
+		return &Nav3D{HeadingNav: HeadingNav{Heading: 90, RealValued: realValued}}
+	},
+	"drift": func() NavMethod {
+		return &DriftNav{NavMethod: &HeadingNav{Heading: 90, RealValued: realValued}, Current: driftCurrent}
+	},
+}
+
+var driftCurrent = Coords{posX: 0, posY: -1}
+
+func navMethodNames() []string {
+	names := []string{}
+	for name := range navMethods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func newNavMethod(name string) (NavMethod, error) {
+	constructor, ok := navMethods[name]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("unknown nav method %q, expected one of %s", name, strings.Join(navMethodNames(), ", ")))
+	}
+
+	return constructor(), nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+type navCase struct {
+	name       string
+	source     string
+	want       Coords
+	distance   int
+	shouldFail bool
+}
+
+func runNavCases(t *testing.T, newMethod func() NavMethod, cases []navCase) {
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			directions, err := compileDirections(c.source)
+			if err != nil {
+				t.Fatalf("compiling %q: %s", c.source, err)
+			}
+
+			ship := &Coords{}
+			distance, err := (&Navigator{Directions: directions, Ship: ship, NavMethod: newMethod()}).Run()
+			if c.shouldFail {
+				if err == nil {
+					t.Fatalf("expected %q to fail, ended at %+v", c.source, *ship)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("running %q: %s", c.source, err)
+			}
+
+			if *ship != c.want {
+				t.Errorf("ship ended at %+v, want %+v", *ship, c.want)
+			}
+			if distance != c.distance {
+				t.Errorf("distance is %d, want %d", distance, c.distance)
+			}
+		})
+	}
+}
+
+func TestHexNav(t *testing.T) {
+	runNavCases(t, func() NavMethod { return &HexNav{Heading: 60} }, []navCase{
+		{name: "forward", source: "F10", want: Coords{posX: 10}, distance: 10},
+		{name: "compass moves", source: "N3 E2", want: Coords{posX: 2, posY: 3}, distance: 5},
+		{name: "hex turn", source: "R60 F4", want: Coords{posX: 4, posY: -4}, distance: 4},
+		{name: "turn back to north", source: "L60 F2 S5", want: Coords{posY: -3}, distance: 3},
+		{name: "west", source: "R180 F2 W1", want: Coords{posX: -3}, distance: 3},
+		{name: "full turn", source: "R360 F1 L720 F1", want: Coords{posX: 2}, distance: 2},
+		{name: "turn between sides", source: "L30", shouldFail: true},
+		{name: "compass turn", source: "R90", shouldFail: true},
+	})
+}
+
+func TestHexRoute(t *testing.T) {
+	route, err := findRoute(RouteQuery{
+		Method:       "hex",
+		Target:       Coords{posX: 3, posY: 3},
+		Instructions: "LRF",
+		Step:         1,
+		MaxAmount:    3,
+		TurnStep:     60,
+		MaxStates:    100000,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := formatRoute(route); got != "F3 L60 F3" {
+		t.Errorf("route is %q, want F3 L60 F3", got)
+	}
+}
+
+func TestNav3D(t *testing.T) {
+	runNavCases(t, func() NavMethod { return &Nav3D{HeadingNav: HeadingNav{Heading: 90}} }, []navCase{
+		{name: "climb and sail", source: "F10 U5 D2 N3", want: Coords{posX: 10, posY: 3, posZ: 3}, distance: 16},
+		{name: "turn then forward", source: "U1 L90 F2", want: Coords{posY: 2, posZ: 1}, distance: 3},
+		{name: "dive below", source: "D7", want: Coords{posZ: -7}, distance: 7},
+		{name: "unknown instruction", source: "Q1", shouldFail: true},
+	})
+}
+
+func TestDriftNav(t *testing.T) {
+	runNavCases(t, func() NavMethod {
+		return &DriftNav{NavMethod: &HeadingNav{Heading: 90}, Current: Coords{posY: -1}}
+	}, []navCase{
+		{name: "drift after every step", source: "F10 N2", want: Coords{posX: 10, posY: 0}, distance: 10},
+		{name: "turns drift too", source: "R90", want: Coords{posY: -1}, distance: 1},
+		{name: "inner errors pass through", source: "Q1", shouldFail: true},
+	})
+
+	runNavCases(t, func() NavMethod {
+		return &DriftNav{NavMethod: &HeadingNav{Heading: 90, RealValued: true}, Current: Coords{posY: -1}}
+	}, []navCase{
+		{name: "real heading inner", source: "F10 F10 F10", want: Coords{posX: 30, posY: -3}, distance: 33},
+		{name: "real heading inner turning", source: "F10 L45 R45 N1", want: Coords{posX: 10, posY: -3}, distance: 13},
+	})
+
+	runNavCases(t, func() NavMethod {
+		return &DriftNav{
+			NavMethod: &WaypointNav{Waypoint: &Coords{posX: 10, posY: 1}, Start: Coords{posX: 10, posY: 1}, RealValued: true},
+			Current:   Coords{posX: 1},
+		}
+	}, []navCase{
+		{name: "real waypoint inner", source: "F2 F1", want: Coords{posX: 32, posY: 3}, distance: 35},
+	})
+
+	runNavCases(t, func() NavMethod {
+		return &DriftNav{
+			NavMethod: &WaypointNav{Waypoint: &Coords{posX: 10, posY: 1}, Start: Coords{posX: 10, posY: 1}},
+			Current:   Coords{posX: 1},
+		}
+	}, []navCase{
+		{name: "waypoint inner", source: "F2 N4", want: Coords{posX: 22, posY: 2}, distance: 24},
+		{name: "reset reaches the waypoint", source: "N4 reset F1", want: Coords{posX: 12, posY: 1}, distance: 13},
+	})
+}
+
+func TestDriftNavWaypoint(t *testing.T) {
+	cases := []struct {
+		name     string
+		inner    NavMethod
+		waypoint bool
+	}{
+		{name: "heading", inner: &HeadingNav{Heading: 90}},
+		{name: "waypoint", inner: &WaypointNav{Waypoint: &Coords{posX: 10, posY: 1}}, waypoint: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			navigator := &Navigator{
+				Directions: []*Direction{{Instruction: "F", Amount: 1}},
+				Ship:       &Coords{},
+				NavMethod:  &DriftNav{NavMethod: c.inner},
+			}
+			if _, err := navigator.Run(); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, point := range navigator.Track {
+				if (point.Waypoint != nil) != c.waypoint {
+					t.Fatalf("step %d recorded waypoint %v, want one: %t", point.Step, point.Waypoint, c.waypoint)
+				}
+			}
+		})
+	}
+}
+
+func TestNewNavMethod(t *testing.T) {
+	cases := []struct {
+		name       string
+		want       string
+		shouldFail bool
+	}{
+		{name: "heading", want: "*main.HeadingNav"},
+		{name: "waypoint", want: "*main.WaypointNav"},
+		{name: "hex", want: "*main.HexNav"},
+		{name: "3d", want: "*main.Nav3D"},
+		{name: "drift", want: "*main.DriftNav"},
+		{name: "square", shouldFail: true},
+		{name: "", shouldFail: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			method, err := newNavMethod(c.name)
+			if c.shouldFail {
+				if err == nil {
+					t.Fatalf("expected %q to be unknown, got %T", c.name, method)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := fmt.Sprintf("%T", method); got != c.want {
+				t.Errorf("got %s, want %s", got, c.want)
+			}
+		})
+	}
+
+	first, _ := newNavMethod("waypoint")
+	second, _ := newNavMethod("waypoint")
+	first.Advance(&Direction{Instruction: "F", Amount: 1}, &Coords{})
+	first.Turn(&Direction{Instruction: "R", Amount: 90}, &Coords{})
+	if first.(*WaypointNav).Waypoint == second.(*WaypointNav).Waypoint || *second.(*WaypointNav).Waypoint != (Coords{posX: 10, posY: 1}) {
+		t.Errorf("registry constructors share state between methods")
+	}
+}
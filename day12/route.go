@@ -0,0 +1,222 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type ClonableNav interface {
+	NavMethod
+	Clone() NavMethod
+	State() string
+}
+
+func (h *HeadingNav) Clone() NavMethod {
+	clone := *h
+	if h.ship != nil {
+		ship := *h.ship
+		clone.ship = &ship
+	}
+
+	return &clone
+}
+
+func (h *HeadingNav) State() string {
+	if h.ship != nil {
+		return fmt.Sprintf("h%d %g,%g", h.Heading, h.ship.posX, h.ship.posY)
+	}
+
+	return fmt.Sprintf("h%d", h.Heading)
+}
+
+func (w *WaypointNav) Clone() NavMethod {
+	clone := *w
+	waypoint := *w.Waypoint
+	clone.Waypoint = &waypoint
+	if w.ship != nil {
+		ship, realWaypoint := *w.ship, *w.waypoint
+		clone.ship, clone.waypoint = &ship, &realWaypoint
+	}
+
+	return &clone
+}
+
+func (w *WaypointNav) State() string {
+	if w.ship != nil {
+		return fmt.Sprintf("w%g,%g %g,%g", w.waypoint.posX, w.waypoint.posY, w.ship.posX, w.ship.posY)
+	}
+
+	return fmt.Sprintf("w%d,%d", w.Waypoint.posX, w.Waypoint.posY)
+}
+
+func (h *HexNav) Clone() NavMethod {
+	clone := *h
+	return &clone
+}
+
+func (h *HexNav) State() string {
+	return fmt.Sprintf("x%d", h.Heading)
+}
+
+func (n *Nav3D) Clone() NavMethod {
+	return &Nav3D{HeadingNav: *n.HeadingNav.Clone().(*HeadingNav)}
+}
+
+func (d *DriftNav) Clone() NavMethod {
+	inner, ok := d.NavMethod.(ClonableNav)
+	if !ok {
+		return nil
+	}
+
+	return &DriftNav{NavMethod: inner.Clone(), Current: d.Current}
+}
+
+func (d *DriftNav) State() string {
+	if inner, ok := d.NavMethod.(ClonableNav); ok {
+		return "d" + inner.State()
+	}
+
+	return "d"
+}
+
+type RouteQuery struct {
+	Method       string
+	Start        Coords
+	Target       Coords
+	Instructions string
+	Step         int
+	MaxAmount    int
+	TurnStep     int
+	MaxStates    int
+}
+
+type searchNode struct {
+	ship   Coords
+	nav    ClonableNav
+	parent *searchNode
+	via    *Direction
+}
+
+func (q RouteQuery) moves() ([]*Direction, error) {
+	if q.Step < 1 || q.MaxAmount < q.Step {
+		return nil, errors.New(fmt.Sprintf("amount step %d and maximum %d do not give any amounts", q.Step, q.MaxAmount))
+	}
+	if q.TurnStep < 1 {
+		return nil, errors.New(fmt.Sprintf("turn step must be positive, got %d", q.TurnStep))
+	}
+
+	moves := []*Direction{}
+	for _, instruction := range strings.Split(q.Instructions, "") {
+		switch instruction {
+		case "L", "R":
+			for amount := q.TurnStep; amount < 360; amount += q.TurnStep {
+				moves = append(moves, &Direction{Instruction: instruction, Amount: amount})
+			}
+		default:
+			for amount := q.Step; amount <= q.MaxAmount; amount += q.Step {
+				moves = append(moves, &Direction{Instruction: instruction, Amount: amount})
+			}
+		}
+	}
+
+	return moves, nil
+}
+
+func findRoute(q RouteQuery) ([]*Direction, error) {
+	moves, err := q.moves()
+	if err != nil {
+		return nil, err
+	}
+
+	method, err := newNavMethod(q.Method)
+	if err != nil {
+		return nil, err
+	}
+	nav, ok := method.(ClonableNav)
+	if !ok || nav.Clone() == nil {
+		return nil, errors.New(fmt.Sprintf("nav method %s cannot be searched", q.Method))
+	}
+
+	key := func(n *searchNode) string {
+		return fmt.Sprintf("%d,%d,%d %s", n.ship.posX, n.ship.posY, n.ship.posZ, n.nav.State())
+	}
+
+	start := &searchNode{ship: q.Start, nav: nav}
+	seen := map[string]bool{key(start): true}
+	queue := []*searchNode{start}
+
+	if start.ship == q.Target {
+		return []*Direction{}, nil
+	}
+
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+
+		for _, move := range moves {
+			next := &searchNode{
+				ship:   node.ship,
+				nav:    node.nav.Clone().(ClonableNav),
+				parent: node,
+				via:    move,
+			}
+
+			nav := &Navigator{Ship: &next.ship, NavMethod: next.nav}
+			if err := nav.apply(move); err != nil {
+				continue
+			}
+
+			if next.ship == q.Target {
+				return next.route(), nil
+			}
+
+			k := key(next)
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+
+			if len(seen) > q.MaxStates {
+				return nil, errors.New(fmt.Sprintf("no route found within %d states", q.MaxStates))
+			}
+			queue = append(queue, next)
+		}
+	}
+
+	return nil, errors.New("target is unreachable with the allowed instructions")
+}
+
+func (n *searchNode) route() []*Direction {
+	route := []*Direction{}
+	for node := n; node.parent != nil; node = node.parent {
+		route = append([]*Direction{node.via}, route...)
+	}
+
+	return route
+}
+
+func formatRoute(route []*Direction) string {
+	parts := []string{}
+	for _, direction := range route {
+		parts = append(parts, fmt.Sprintf("%s%d", direction.Instruction, direction.Amount))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func parseCoords(input string) (Coords, error) {
+	parts := strings.Split(input, ",")
+	if len(parts) < 2 || len(parts) > 3 {
+		return Coords{}, errors.New(fmt.Sprintf("expected x,y or x,y,z but got %q", input))
+	}
+
+	values := make([]int, 3)
+	for i, part := range parts {
+		if _, err := fmt.Sscan(strings.TrimSpace(part), &values[i]); err != nil {
+			return Coords{}, errors.New(fmt.Sprintf("invalid coordinate %q", part))
+		}
+	}
+
+	return Coords{posX: values[0], posY: values[1], posZ: values[2]}, nil
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type TrackPoint struct {
+	Step      int
+	Direction *Direction
+	Ship      Coords
+	Waypoint  *Coords
+}
+
+// WaypointHolder is implemented by nav methods that may steer by a waypoint.
+// WaypointPosition reports false when there is no waypoint to record.
+type WaypointHolder interface {
+	WaypointPosition() (Coords, bool)
+}
+
+func (w *WaypointNav) WaypointPosition() (Coords, bool) {
+	return *w.Waypoint, true
+}
+
+func (n *Navigator) record(step int, direction *Direction) {
+	point := TrackPoint{
+		Step:      step,
+		Direction: direction,
+		Ship:      *n.Ship,
+	}
+
+	if holder, ok := n.NavMethod.(WaypointHolder); ok {
+		if waypoint, ok := holder.WaypointPosition(); ok {
+			waypoint.posX += n.Ship.posX
+			waypoint.posY += n.Ship.posY
+			point.Waypoint = &waypoint
+		}
+	}
+
+	n.Track = append(n.Track, point)
+}
+
+func writeTrackCSV(w io.Writer, track []TrackPoint) error {
+	out := csv.NewWriter(w)
+	if err := out.Write([]string{"step", "instruction", "ship_x", "ship_y", "waypoint_x", "waypoint_y"}); err != nil {
+		return err
+	}
+
+	for _, point := range track {
+		instruction := ""
+		switch {
+		case point.Direction == nil:
+		case point.Direction.Instruction == resetInstruction:
+			instruction = resetInstruction
+		default:
+			instruction = fmt.Sprintf("%s%d", point.Direction.Instruction, point.Direction.Amount)
+		}
+
+		row := []string{
+			strconv.Itoa(point.Step),
+			instruction,
+			strconv.Itoa(point.Ship.posX),
+			strconv.Itoa(point.Ship.posY),
+			"",
+			"",
+		}
+		if point.Waypoint != nil {
+			row[4] = strconv.Itoa(point.Waypoint.posX)
+			row[5] = strconv.Itoa(point.Waypoint.posY)
+		}
+
+		if err := out.Write(row); err != nil {
+			return err
+		}
+	}
+
+	out.Flush()
+	return out.Error()
+}
+
+type bounds struct {
+	minX int
+	minY int
+	maxX int
+	maxY int
+}
+
+func trackBounds(track []TrackPoint) bounds {
+	b := bounds{
+		minX: track[0].Ship.posX,
+		minY: track[0].Ship.posY,
+		maxX: track[0].Ship.posX,
+		maxY: track[0].Ship.posY,
+	}
+
+	grow := func(c Coords) {
+		if c.posX < b.minX {
+			b.minX = c.posX
+		}
+		if c.posX > b.maxX {
+			b.maxX = c.posX
+		}
+		if c.posY < b.minY {
+			b.minY = c.posY
+		}
+		if c.posY > b.maxY {
+			b.maxY = c.posY
+		}
+	}
+
+	for _, point := range track {
+		grow(point.Ship)
+		if point.Waypoint != nil {
+			grow(*point.Waypoint)
+		}
+	}
+
+	return b
+}
+
+func (b bounds) width() int {
+	return b.maxX - b.minX + 1
+}
+
+func (b bounds) height() int {
+	return b.maxY - b.minY + 1
+}
+
+func writeTrackSVG(w io.Writer, track []TrackPoint) error {
+	b := trackBounds(track)
+	margin := (b.width() + b.height()) / 40
+	if margin < 1 {
+		margin = 1
+	}
+	stroke := float64(b.width()+b.height()) / 1000
+	if stroke < 0.5 {
+		stroke = 0.5
+	}
+
+	point := func(c Coords) string {
+		return fmt.Sprintf("%d,%d", c.posX, -c.posY)
+	}
+
+	var ship, waypoint []string
+	for _, p := range track {
+		ship = append(ship, point(p.Ship))
+		if p.Waypoint != nil {
+			waypoint = append(waypoint, point(*p.Waypoint))
+		}
+	}
+
+	start := track[0].Ship
+	end := track[len(track)-1].Ship
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="%d %d %d %d">`+"\n",
+		b.minX-margin, -b.maxY-margin, b.width()+2*margin, b.height()+2*margin))
+	if len(waypoint) > 0 {
+		sb.WriteString(fmt.Sprintf(`  <polyline fill="none" stroke="#9ecae1" stroke-width="%g" points="%s"/>`+"\n", stroke, strings.Join(waypoint, " ")))
+	}
+	sb.WriteString(fmt.Sprintf(`  <polyline fill="none" stroke="#08519c" stroke-width="%g" points="%s"/>`+"\n", stroke, strings.Join(ship, " ")))
+	sb.WriteString(fmt.Sprintf(`  <circle cx="%d" cy="%d" r="%g" fill="#31a354"/>`+"\n", start.posX, -start.posY, stroke*4))
+	sb.WriteString(fmt.Sprintf(`  <circle cx="%d" cy="%d" r="%g" fill="#de2d26"/>`+"\n", end.posX, -end.posY, stroke*4))
+	sb.WriteString("</svg>\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func writeTrackPNG(w io.Writer, track []TrackPoint, size int) error {
+	b := trackBounds(track)
+	longest := b.width()
+	if b.height() > longest {
+		longest = b.height()
+	}
+	scale := float64(size-1) / float64(longest)
+	if scale > 1 {
+		scale = 1
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, int(float64(b.width())*scale)+1, int(float64(b.height())*scale)+1))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+
+	project := func(c Coords) (int, int) {
+		return int(float64(c.posX-b.minX) * scale), int(float64(b.maxY-c.posY) * scale)
+	}
+
+	waypointColour := color.RGBA{R: 0x9e, G: 0xca, B: 0xe1, A: 0xff}
+	shipColour := color.RGBA{R: 0x08, G: 0x51, B: 0x9c, A: 0xff}
+	for i := 1; i < len(track); i++ {
+		if track[i-1].Waypoint != nil && track[i].Waypoint != nil {
+			x0, y0 := project(*track[i-1].Waypoint)
+			x1, y1 := project(*track[i].Waypoint)
+			drawLine(img, x0, y0, x1, y1, waypointColour)
+		}
+	}
+	for i := 1; i < len(track); i++ {
+		x0, y0 := project(track[i-1].Ship)
+		x1, y1 := project(track[i].Ship)
+		drawLine(img, x0, y0, x1, y1, shipColour)
+	}
+
+	x, y := project(track[0].Ship)
+	drawMarker(img, x, y, color.RGBA{R: 0x31, G: 0xa3, B: 0x54, A: 0xff})
+	x, y = project(track[len(track)-1].Ship)
+	drawMarker(img, x, y, color.RGBA{R: 0xde, G: 0x2d, B: 0x26, A: 0xff})
+
+	return png.Encode(w, img)
+}
+
+func drawLine(img *image.RGBA, x0 int, y0 int, x1 int, y1 int, c color.Color) {
+	dx := abs(x1 - x0)
+	dy := -abs(y1 - y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+
+	e := dx + dy
+	for {
+		img.Set(x0, y0, c)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+func drawMarker(img *image.RGBA, x int, y int, c color.Color) {
+	for dy := -3; dy <= 3; dy++ {
+		for dx := -3; dx <= 3; dx++ {
+			img.Set(x+dx, y+dy, c)
+		}
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+type Congruence struct {
+	Residue *big.Int
+	Modulus *big.Int
+}
+
+type Solution struct {
+	Residue *big.Int
+	Modulus *big.Int
+}
+
+func (s *Solution) String() string {
+	return fmt.Sprintf("t ≡ %s (mod %s)", s.Residue, s.Modulus)
+}
+
+func newCongruence(residue int64, modulus int64) Congruence {
+	return Congruence{
+		Residue: big.NewInt(residue),
+		Modulus: big.NewInt(modulus),
+	}
+}
+
+func extendedGCD(a *big.Int, b *big.Int) (gcd *big.Int, x *big.Int, y *big.Int) {
+	oldR, r := new(big.Int).Set(a), new(big.Int).Set(b)
+	oldS, s := big.NewInt(1), big.NewInt(0)
+	oldT, t := big.NewInt(0), big.NewInt(1)
+
+	for r.Sign() != 0 {
+		quotient := new(big.Int).Quo(oldR, r)
+		oldR, r = r, new(big.Int).Sub(oldR, new(big.Int).Mul(quotient, r))
+		oldS, s = s, new(big.Int).Sub(oldS, new(big.Int).Mul(quotient, s))
+		oldT, t = t, new(big.Int).Sub(oldT, new(big.Int).Mul(quotient, t))
+	}
+
+	return oldR, oldS, oldT
+}
+
+func solveCRT(congruences []Congruence) (*Solution, error) {
+	solution := &Solution{
+		Residue: big.NewInt(0),
+		Modulus: big.NewInt(1),
+	}
+
+	for i, c := range congruences {
+		if c.Modulus.Sign() <= 0 {
+			return nil, errors.New(fmt.Sprintf("congruence %d has non-positive modulus %s", i, c.Modulus))
+		}
+
+		residue := new(big.Int).Mod(c.Residue, c.Modulus)
+		gcd, p, _ := extendedGCD(solution.Modulus, c.Modulus)
+
+		diff := new(big.Int).Sub(residue, solution.Residue)
+		if new(big.Int).Mod(diff, gcd).Sign() != 0 {
+			return nil, errors.New(fmt.Sprintf("congruence %d (t ≡ %s mod %s) contradicts %s", i, residue, c.Modulus, solution))
+		}
+
+		lcm := new(big.Int).Mul(solution.Modulus, new(big.Int).Quo(c.Modulus, gcd))
+		step := new(big.Int).Quo(diff, gcd)
+		step.Mul(step, p)
+		step.Mod(step, new(big.Int).Quo(c.Modulus, gcd))
+
+		solution.Residue.Add(solution.Residue, step.Mul(step, solution.Modulus))
+		solution.Residue.Mod(solution.Residue, lcm)
+		solution.Modulus = lcm
+	}
+
+	return solution, nil
+}
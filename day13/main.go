@@ -1,64 +1,30 @@
 package main
 
 import (
-	"bufio"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"os"
-	"sort"
-	"strconv"
 	"strings"
 )
 
-func parseSchedule() (departureTime int, buses []int, err error) {
-	f, err := os.Open("input.txt")
+var inputPath = "input.txt"
+
+func getSchedule() (*Schedule, error) {
+	f, err := os.Open(inputPath)
 	if err != nil {
-		return 0, nil, err
+		return nil, err
 	}
 	defer f.Close()
 
-	scanner := bufio.NewScanner(f)
-
-	for scanner.Scan() {
-		err := scanner.Err()
-		if err != nil {
-			return 0, nil, err
-		}
-
-		if departureTime == 0 {
-			departureTime, err = strconv.Atoi(scanner.Text())
-			if err != nil {
-				return 0, nil, err
-			}
-		} else {
-			busesRaw := strings.Split(scanner.Text(), ",")
-			for _, bus := range busesRaw {
-				if bus == "x" {
-					buses = append(buses, -1)
-					continue
-				}
-
-				busInt, err := strconv.Atoi(bus)
-				if err != nil {
-					return 0, nil, err
-				}
-
-				buses = append(buses, busInt)
-			}
-		}
-	}
-
-	return departureTime, buses, nil
+	return parseSchedule(f)
 }
 
 func part1(departureTime int, buses []int) int {
 	closestDeparture := -1
 	closestBus := -1
 	for _, bus := range buses {
-		if bus == -1 {
-			continue
-		}
-
 		nextDeparture := departureTime + (bus - (departureTime % bus))
 
 		if closestDeparture == -1 || nextDeparture-departureTime < closestDeparture {
@@ -70,37 +36,92 @@ func part1(departureTime int, buses []int) int {
 	return closestDeparture * closestBus
 }
 
-func part2(buses []int) int {
-	departure := 0
-	increment := 1
-	sortedBuses := [][]int{}
+func part2(set *ConstraintSet) (*Solution, error) {
+	return solveCRT(set.Congruences())
+}
 
-	for pos, id := range buses {
-		if id == -1 {
-			continue
+func queryTimetable(timetable *Timetable, from int, next int, pattern string, coincide bool, grid string) error {
+	if next > 0 {
+		departures := timetable.NextDepartures(from, next)
+		for _, bus := range timetable.Buses {
+			fmt.Printf("bus %d: %s\n", bus, strings.Trim(fmt.Sprint(departures[bus]), "[]"))
+		}
+	}
+
+	if pattern != "" {
+		offsets, err := parsePattern(pattern)
+		if err != nil {
+			return err
 		}
 
-		sortedBuses = append(sortedBuses, []int{pos, id})
+		solution, err := timetable.FirstPattern(offsets)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("pattern %s first departs at %s and repeats every %s\n", pattern, solution.Residue, solution.Modulus)
 	}
 
-	sort.Slice(sortedBuses, func(i, j int) bool { return sortedBuses[i][1] > sortedBuses[j][1] })
+	if coincide {
+		at, gap, err := timetable.Coincidence(from)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("every bus departs together at %s, %s minutes after %d\n", at, gap, from)
+	}
 
-	for _, bus := range sortedBuses {
-		for (departure+bus[0])%bus[1] != 0 {
-			departure += increment
+	if grid != "" {
+		var start, end int
+		if _, err := fmt.Sscanf(grid, "%d,%d", &start, &end); err != nil || end < start {
+			return errors.New(fmt.Sprintf("expected from,to for the grid but got %q", grid))
 		}
-		increment *= bus[1]
+		fmt.Print(timetable.Grid(start, end))
 	}
 
-	return departure
+	return nil
 }
 
 func main() {
-	departureTime, buses, err := parseSchedule()
+	next := flag.Int("next", 0, "print this many upcoming departures of each bus")
+	from := flag.Int("from", -1, "time used by -next and -coincide (defaults to the schedule's departure time)")
+	pattern := flag.String("pattern", "", "find when buses depart in a bus:offset,bus:offset pattern")
+	coincide := flag.Bool("coincide", false, "print how long until every bus departs at once")
+	grid := flag.String("grid", "", "print a timetable grid between two timestamps given as from,to")
+	flag.StringVar(&inputPath, "input", inputPath, "file to read the schedule from")
+	flag.Parse()
+
+	schedule, err := getSchedule()
 	if err != nil {
 		log.Fatalln(fmt.Sprintf("Error parsing schedule: %s", err))
 	}
 
+	departureTime := schedule.DepartureTime
+	buses := schedule.Sets[0].Buses()
+
+	if *from < 0 {
+		*from = departureTime
+	}
+	if err := queryTimetable(newTimetable(buses), *from, *next, *pattern, *coincide, *grid); err != nil {
+		log.Fatalln(fmt.Sprintf("Error querying timetable: %s", err))
+	}
+
 	log.Println(fmt.Sprintf("The answer to part 1 is %d", part1(departureTime, buses)))
-	log.Println(fmt.Sprintf("The answer to part 2 is %d", part2(buses)))
+	failed := 0
+	for i, set := range schedule.Sets {
+		solution, err := part2(set)
+		if err != nil {
+			log.Println(fmt.Sprintf("Error solving part 2 for %s: %s", set.Name, err))
+			failed++
+			continue
+		}
+
+		if i == 0 {
+			log.Println(fmt.Sprintf("The answer to part 2 is %s (every %s)", solution.Residue, solution.Modulus))
+		} else {
+			log.Println(fmt.Sprintf("The answer to part 2 for %s is %s (every %s)", set.Name, solution.Residue, solution.Modulus))
+		}
+	}
+
+	if failed > 0 {
+		log.Fatalln(fmt.Sprintf("Part 2 failed for %d of %d constraint sets", failed, len(schedule.Sets)))
+	}
 }
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type Constraint struct {
+	Bus    int
+	Offset int
+}
+
+type ConstraintSet struct {
+	Name        string
+	Constraints []Constraint
+}
+
+type Schedule struct {
+	DepartureTime int
+	Sets          []*ConstraintSet
+}
+
+var (
+	departureRe  = regexp.MustCompile(`^departure\s*:?\s*(\d+)$`)
+	setHeaderRe  = regexp.MustCompile(`^\[(.+)\]$`)
+	busListRe    = regexp.MustCompile(`^buses\s*:?\s*(.+)$`)
+	constraintRe = regexp.MustCompile(`^(-?\d+)\s*@\s*(-?\d+)$`)
+)
+
+func (c *ConstraintSet) Buses() []int {
+	buses := []int{}
+	for _, constraint := range c.Constraints {
+		buses = append(buses, constraint.Bus)
+	}
+
+	return buses
+}
+
+func (c *ConstraintSet) Congruences() []Congruence {
+	congruences := []Congruence{}
+	for _, constraint := range c.Constraints {
+		congruences = append(congruences, newCongruence(int64(-constraint.Offset), int64(constraint.Bus)))
+	}
+
+	return congruences
+}
+
+func (c *ConstraintSet) add(bus int, offset int) error {
+	if bus <= 0 {
+		return errors.New(fmt.Sprintf("bus ID must be positive, got %d", bus))
+	}
+	for _, constraint := range c.Constraints {
+		if constraint.Bus == bus {
+			return errors.New(fmt.Sprintf("bus %d appears more than once in %s", bus, c.Name))
+		}
+	}
+
+	c.Constraints = append(c.Constraints, Constraint{Bus: bus, Offset: offset})
+	return nil
+}
+
+func (c *ConstraintSet) addBusList(list string) error {
+	for offset, bus := range strings.Split(list, ",") {
+		bus = strings.TrimSpace(bus)
+		if bus == "x" {
+			continue
+		}
+
+		busInt, err := strconv.Atoi(bus)
+		if err != nil {
+			return errors.New(fmt.Sprintf("invalid bus ID %q", bus))
+		}
+
+		if err := c.add(busInt, offset); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func parseSchedule(r io.Reader) (*Schedule, error) {
+	scanner := bufio.NewScanner(r)
+	schedule := &Schedule{}
+	var current *ConstraintSet
+	departureSeen := false
+
+	fail := func(line int, err error) error {
+		return errors.New(fmt.Sprintf("line %d: %s", line, err))
+	}
+
+	newSet := func(name string) *ConstraintSet {
+		set := &ConstraintSet{Name: name}
+		schedule.Sets = append(schedule.Sets, set)
+		return set
+	}
+
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			// A blank line ends a set; a [name] header also starts a new one.
+			if current != nil && len(current.Constraints) > 0 {
+				current = nil
+			}
+			continue
+		}
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if !departureSeen {
+			if matches := departureRe.FindStringSubmatch(line); matches != nil {
+				line = matches[1]
+			}
+
+			departureTime, err := strconv.Atoi(line)
+			if err != nil {
+				return nil, fail(lineNo, errors.New("expected the departure time first"))
+			}
+			schedule.DepartureTime = departureTime
+			departureSeen = true
+			continue
+		}
+
+		if matches := setHeaderRe.FindStringSubmatch(line); matches != nil {
+			current = newSet(strings.TrimSpace(matches[1]))
+			continue
+		}
+
+		if matches := busListRe.FindStringSubmatch(line); matches != nil {
+			line = matches[1]
+		}
+
+		if current == nil {
+			current = newSet(fmt.Sprintf("set %d", len(schedule.Sets)+1))
+		}
+
+		if !strings.Contains(line, "@") {
+			if err := current.addBusList(line); err != nil {
+				return nil, fail(lineNo, err)
+			}
+			continue
+		}
+
+		for _, field := range strings.Split(line, ",") {
+			matches := constraintRe.FindStringSubmatch(strings.TrimSpace(field))
+			if matches == nil {
+				return nil, fail(lineNo, errors.New(fmt.Sprintf("expected bus@offset but got %q", strings.TrimSpace(field))))
+			}
+
+			bus, err := strconv.Atoi(matches[1])
+			if err != nil {
+				return nil, fail(lineNo, errors.New(fmt.Sprintf("invalid bus ID %q", matches[1])))
+			}
+			offset, err := strconv.Atoi(matches[2])
+			if err != nil {
+				return nil, fail(lineNo, errors.New(fmt.Sprintf("invalid offset %q for bus %d", matches[2], bus)))
+			}
+			if err := current.add(bus, offset); err != nil {
+				return nil, fail(lineNo, err)
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if !departureSeen {
+		return nil, errors.New("schedule is empty")
+	}
+	if len(schedule.Sets) == 0 {
+		return nil, errors.New("schedule has no buses")
+	}
+	for _, set := range schedule.Sets {
+		if len(set.Constraints) == 0 {
+			return nil, errors.New(fmt.Sprintf("%s has no buses", set.Name))
+		}
+	}
+
+	return schedule, nil
+}
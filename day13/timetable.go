@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Timetable struct {
+	Buses []int
+}
+
+func newTimetable(buses []int) *Timetable {
+	return &Timetable{Buses: buses}
+}
+
+func nextDeparture(bus int, from int) int {
+	if from%bus == 0 {
+		return from
+	}
+
+	return from + (bus - from%bus)
+}
+
+func (t *Timetable) NextDepartures(from int, n int) map[int][]int {
+	departures := map[int][]int{}
+	for _, bus := range t.Buses {
+		next := nextDeparture(bus, from)
+		for i := 0; i < n; i++ {
+			departures[bus] = append(departures[bus], next+i*bus)
+		}
+	}
+
+	return departures
+}
+
+func (t *Timetable) FirstPattern(offsets map[int]int) (*Solution, error) {
+	if len(offsets) == 0 {
+		return nil, errors.New("no buses in pattern")
+	}
+
+	buses := []int{}
+	for bus := range offsets {
+		if !t.serves(bus) {
+			return nil, errors.New(fmt.Sprintf("bus %d is not in the timetable", bus))
+		}
+		buses = append(buses, bus)
+	}
+	sort.Ints(buses)
+
+	congruences := []Congruence{}
+	for _, bus := range buses {
+		congruences = append(congruences, newCongruence(int64(-offsets[bus]), int64(bus)))
+	}
+
+	return solveCRT(congruences)
+}
+
+func (t *Timetable) Coincidence(from int) (at *big.Int, gap *big.Int, err error) {
+	offsets := map[int]int{}
+	for _, bus := range t.Buses {
+		offsets[bus] = 0
+	}
+
+	solution, err := t.FirstPattern(offsets)
+	if err != nil {
+		return nil, nil, err
+	}
+	start := big.NewInt(int64(from))
+
+	at = new(big.Int).Add(start, solution.Modulus)
+	at.Sub(at, big.NewInt(1))
+	at.Quo(at, solution.Modulus)
+	at.Mul(at, solution.Modulus)
+
+	return at, new(big.Int).Sub(at, start), nil
+}
+
+func (t *Timetable) serves(bus int) bool {
+	for _, id := range t.Buses {
+		if id == bus {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (t *Timetable) Grid(from int, to int) string {
+	var sb strings.Builder
+	width := len(strconv.Itoa(to))
+	if width < len("time") {
+		width = len("time")
+	}
+
+	header := fmt.Sprintf("%-*s", width, "time")
+	for _, bus := range t.Buses {
+		header += fmt.Sprintf("   bus %-3d", bus)
+	}
+	sb.WriteString(strings.TrimRight(header, " ") + "\n")
+
+	for minute := from; minute <= to; minute++ {
+		row := fmt.Sprintf("%-*d", width, minute)
+		for _, bus := range t.Buses {
+			mark := "."
+			if minute%bus == 0 {
+				mark = "D"
+			}
+			row += fmt.Sprintf("     %-5s", mark)
+		}
+		sb.WriteString(strings.TrimRight(row, " ") + "\n")
+	}
+
+	return sb.String()
+}
+
+func parsePattern(input string) (map[int]int, error) {
+	offsets := map[int]int{}
+	for _, field := range strings.Split(input, ",") {
+		parts := strings.Split(strings.TrimSpace(field), ":")
+		if len(parts) != 2 {
+			return nil, errors.New(fmt.Sprintf("expected bus:offset but got %q", field))
+		}
+
+		bus, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return nil, err
+		}
+		offset, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return nil, err
+		}
+
+		offsets[bus] = offset
+	}
+
+	return offsets, nil
+}
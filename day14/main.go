@@ -3,34 +3,29 @@ package main
 import (
 	"bufio"
 	"errors"
+	"flag"
 	"fmt"
 	"log"
-	"math"
+	"math/big"
 	"os"
 	"regexp"
 	"strconv"
-	"strings"
 )
 
 type MaskSet struct {
-	Mask     string
+	Mask     Mask
 	Registry []Register
 }
 
 type Register struct {
-	Address int
-	Value   int
+	Address uint64
+	Value   uint64
 }
 
-func reverse(str string) (result string) {
-	for _, v := range str {
-		result = string(v) + result
-	}
-	return
-}
+var inputPath = "input.txt"
 
 func parseInput() (maskSets []MaskSet, err error) {
-	f, err := os.Open("input.txt")
+	f, err := os.Open(inputPath)
 	if err != nil {
 		return nil, err
 	}
@@ -53,8 +48,13 @@ func parseInput() (maskSets []MaskSet, err error) {
 				currentMask = nil
 			}
 
+			mask, err := parseMask(line[7:])
+			if err != nil {
+				return nil, err
+			}
+
 			currentMask = &MaskSet{
-				Mask: reverse(line[7:]),
+				Mask: mask,
 			}
 		} else {
 			re := regexp.MustCompile(`^mem\[(\d+)\] = (\d+)$`)
@@ -63,15 +63,25 @@ func parseInput() (maskSets []MaskSet, err error) {
 				return nil, errors.New("invalid input")
 			}
 
-			address, err := strconv.Atoi(matches[1])
+			address, err := strconv.ParseUint(matches[1], 10, 64)
 			if err != nil {
 				return nil, err
 			}
+			if err := checkWord("address", address); err != nil {
+				return nil, err
+			}
 
-			value, err := strconv.Atoi(matches[2])
+			value, err := strconv.ParseUint(matches[2], 10, 64)
 			if err != nil {
 				return nil, err
 			}
+			if err := checkWord("value", value); err != nil {
+				return nil, err
+			}
+
+			if currentMask == nil {
+				return nil, errors.New("memory write before any mask")
+			}
 
 			currentMask.Registry = append(currentMask.Registry, Register{
 				Address: address,
@@ -87,40 +97,7 @@ func parseInput() (maskSets []MaskSet, err error) {
 	return maskSets, nil
 }
 
-func calculateMasks(mask string) (masks []string) {
-	re := regexp.MustCompile(`X`)
-
-	matches := re.FindAllString(mask, -1)
-	format := fmt.Sprintf("%%0%db", len(matches))
-	for i := 0; i < int(math.Pow(float64(2), float64(len(matches)))); i++ {
-		bin := reverse(fmt.Sprintf(format, i))
-		newMask := mask
-		newMask = strings.Replace(newMask, "0", "Y", -1)
-		for _, char := range bin {
-			newMask = strings.Replace(newMask, "X", string(char), 1)
-		}
-
-		newMask = strings.Replace(newMask, "Y", "X", -1)
-		masks = append(masks, newMask)
-	}
-
-	return masks
-}
-
-func applyMask(mask string, value int) int {
-	for pos, bit := range mask {
-		switch string(bit) {
-		case "0":
-			value = clearBit(pos, value)
-		case "1":
-			value = setBit(pos, value)
-		}
-	}
-
-	return value
-}
-
-func sumRegister(register map[int]int) (sum int) {
+func sumRegister(register map[uint64]uint64) (sum uint64) {
 	for _, value := range register {
 		sum += value
 	}
@@ -128,51 +105,69 @@ func sumRegister(register map[int]int) (sum int) {
 	return sum
 }
 
-func setBit(pos int, value int) int {
-	value |= (1 << pos)
-	return value
-}
+func part1(maskSets []MaskSet) uint64 {
+	register := map[uint64]uint64{}
+
+	for _, set := range maskSets {
+		for _, pos := range set.Registry {
+			register[pos.Address] = set.Mask.Apply(pos.Value)
+		}
+	}
 
-func clearBit(pos int, value int) int {
-	mask := ^(1 << pos)
-	value &= mask
-	return value
+	return sumRegister(register)
 }
 
-func part1(maskSets []MaskSet) int {
-	register := map[int]int{}
+func part2(maskSets []MaskSet) *big.Int {
+	memory := &SymbolicMemory{}
 
 	for _, set := range maskSets {
 		for _, pos := range set.Registry {
-			register[pos.Address] = applyMask(set.Mask, pos.Value)
+			memory.Write(addressPattern(set.Mask, pos.Address), pos.Value)
 		}
 	}
 
-	return sumRegister(register)
+	return memory.Sum()
 }
 
-func part2(maskSets []MaskSet) int {
-	register := map[int]int{}
+func part2Expanded(maskSets []MaskSet) (uint64, error) {
+	register := map[uint64]uint64{}
+	decoder := newAddressDecoder()
 
 	for _, set := range maskSets {
 		for _, pos := range set.Registry {
-			masks := calculateMasks(set.Mask)
+			addresses, err := decoder.Addresses(set.Mask, pos.Address)
+			if err != nil {
+				return 0, err
+			}
 
-			for _, mask := range masks {
-				register[applyMask(mask, pos.Address)] = pos.Value
+			for _, address := range addresses {
+				register[address] = pos.Value
 			}
 		}
 	}
 
-	return sumRegister(register)
+	return sumRegister(register), nil
 }
 
 func main() {
+	expand := flag.Bool("expand", false, "also compute part 2 by expanding every floating address")
+	flag.StringVar(&inputPath, "input", inputPath, "file to read the initialization program from")
+	flag.Parse()
+
 	maskSets, err := parseInput()
 	if err != nil {
 		log.Fatalln(fmt.Sprintf("Error parsing input: %s", err))
 	}
 
 	log.Println(fmt.Sprintf("The sum in part 1 is %d", part1(maskSets)))
-	log.Println(fmt.Sprintf("The sum in part 2 is %d", part2(maskSets)))
+	if *expand {
+		sum, err := part2Expanded(maskSets)
+		if err != nil {
+			log.Fatalln(fmt.Sprintf("Error decoding addresses in part 2: %s", err))
+		}
+
+		log.Println(fmt.Sprintf("The sum in part 2 is %d by expanding every address", sum))
+	}
+
+	log.Println(fmt.Sprintf("The sum in part 2 is %s", part2(maskSets)))
 }
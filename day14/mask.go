@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+const (
+	wordBits        = 36
+	wordMask        = uint64(1)<<wordBits - 1
+	maxFloatingBits = 20
+)
+
+type Mask struct {
+	And      uint64
+	Or       uint64
+	Floating uint64
+}
+
+func parseMask(input string) (Mask, error) {
+	if len(input) != wordBits {
+		return Mask{}, errors.New(fmt.Sprintf("mask must be %d bits, got %d: %s", wordBits, len(input), input))
+	}
+
+	mask := Mask{And: wordMask}
+	for i, c := range input {
+		bit := uint64(1) << uint(wordBits-1-i)
+		switch c {
+		case '0':
+			mask.And &^= bit
+		case '1':
+			mask.Or |= bit
+		case 'X':
+			mask.Floating |= bit
+		default:
+			return Mask{}, errors.New(fmt.Sprintf("invalid character %q in mask %s", c, input))
+		}
+	}
+
+	return mask, nil
+}
+
+func checkWord(kind string, value uint64) error {
+	if value&^wordMask != 0 {
+		return errors.New(fmt.Sprintf("%s %d does not fit in %d bits", kind, value, wordBits))
+	}
+
+	return nil
+}
+
+func (m Mask) Apply(value uint64) uint64 {
+	return value&m.And | m.Or
+}
+
+func (m Mask) FloatingBits() int {
+	return bits.OnesCount64(m.Floating)
+}
+
+func (m Mask) FloatingSubsets() ([]uint64, error) {
+	if n := m.FloatingBits(); n > maxFloatingBits {
+		return nil, errors.New(fmt.Sprintf("mask %s has %d floating bits, expanding it would write %d addresses per value (limit is %d bits)", m, n, uint64(1)<<uint(n), maxFloatingBits))
+	}
+
+	subsets := make([]uint64, 0, 1<<uint(m.FloatingBits()))
+	for subset := m.Floating; ; subset = (subset - 1) & m.Floating {
+		subsets = append(subsets, subset)
+		if subset == 0 {
+			break
+		}
+	}
+
+	return subsets, nil
+}
+
+type addressDecoder struct {
+	subsets map[Mask][]uint64
+}
+
+func newAddressDecoder() *addressDecoder {
+	return &addressDecoder{subsets: map[Mask][]uint64{}}
+}
+
+func (d *addressDecoder) Addresses(mask Mask, address uint64) ([]uint64, error) {
+	subsets, ok := d.subsets[mask]
+	if !ok {
+		var err error
+		subsets, err = mask.FloatingSubsets()
+		if err != nil {
+			return nil, err
+		}
+		d.subsets[mask] = subsets
+	}
+
+	base := (address | mask.Or) &^ mask.Floating
+	addresses := make([]uint64, len(subsets))
+	for i, subset := range subsets {
+		addresses[i] = base | subset
+	}
+
+	return addresses, nil
+}
+
+func (m Mask) String() string {
+	out := make([]byte, wordBits)
+	for i := range out {
+		bit := uint64(1) << uint(wordBits-1-i)
+		switch {
+		case m.Floating&bit != 0:
+			out[i] = 'X'
+		case m.Or&bit != 0:
+			out[i] = '1'
+		default:
+			out[i] = '0'
+		}
+	}
+
+	return string(out)
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"math/big"
+	"math/bits"
+)
+
+type Pattern struct {
+	Bits     uint64
+	Floating uint64
+}
+
+type symbolicCell struct {
+	Pattern Pattern
+	Value   uint64
+}
+
+// SymbolicMemory keeps every write as an address pattern. An address ends up
+// holding the value of the newest write whose pattern matches it, so Sum walks
+// the address space from the newest write down, splitting it into regions
+// that each belong to a single write, and never enumerates the addresses.
+type SymbolicMemory struct {
+	cells []symbolicCell
+}
+
+func addressPattern(mask Mask, address uint64) Pattern {
+	return Pattern{
+		Bits:     (address | mask.Or) &^ mask.Floating,
+		Floating: mask.Floating,
+	}
+}
+
+func (p Pattern) fixed() uint64 {
+	return wordMask &^ p.Floating
+}
+
+func (m *SymbolicMemory) Write(p Pattern, value uint64) {
+	m.cells = append(m.cells, symbolicCell{Pattern: p, Value: value})
+}
+
+// claimer works out how many addresses each write owns. Writes are indexed
+// newest-first and the writes that can still match some of a region are kept
+// as a bitset over those indexes, so narrowing a region on one bit is a
+// handful of word operations however many writes there are.
+type claimer struct {
+	patterns   []Pattern
+	owned      []uint64
+	compatible [wordBits][2][]uint64
+	fixes      [wordBits][]uint64
+	scratch    [wordBits + 1][]uint64
+}
+
+func newClaimer(patterns []Pattern) *claimer {
+	words := (len(patterns) + 63) / 64
+	c := &claimer{
+		patterns: patterns,
+		owned:    make([]uint64, len(patterns)),
+	}
+	for bit := 0; bit < wordBits; bit++ {
+		c.compatible[bit][0] = make([]uint64, words)
+		c.compatible[bit][1] = make([]uint64, words)
+		c.fixes[bit] = make([]uint64, words)
+	}
+	for depth := range c.scratch {
+		c.scratch[depth] = make([]uint64, words)
+	}
+
+	for i, p := range patterns {
+		word, mask := i/64, uint64(1)<<uint(i%64)
+		for bit := 0; bit < wordBits; bit++ {
+			switch {
+			case p.Floating&(1<<uint(bit)) != 0:
+				c.compatible[bit][0][word] |= mask
+				c.compatible[bit][1][word] |= mask
+			case p.Bits&(1<<uint(bit)) != 0:
+				c.compatible[bit][1][word] |= mask
+				c.fixes[bit][word] |= mask
+			default:
+				c.compatible[bit][0][word] |= mask
+				c.fixes[bit][word] |= mask
+			}
+		}
+	}
+
+	return c
+}
+
+// claim hands region to the newest write in alive once that write covers all
+// of it. Until then it splits region on one of that write's fixed bits, so
+// one half drops the write and the other gets closer to being covered by it.
+// Of those bits it picks the one the most other live writes also fix.
+func (c *claimer) claim(region Pattern, depth int, alive []uint64, from int) {
+	newest := -1
+	for ; from < len(alive); from++ {
+		if alive[from] != 0 {
+			newest = 64*from + bits.TrailingZeros64(alive[from])
+			break
+		}
+	}
+	if newest < 0 {
+		return
+	}
+
+	open := c.patterns[newest].fixed() & region.Floating
+	if open == 0 {
+		c.owned[newest] += uint64(1) << uint(bits.OnesCount64(region.Floating))
+		return
+	}
+
+	split, best := 0, -1
+	for rest := open; rest != 0; rest &= rest - 1 {
+		bit := bits.TrailingZeros64(rest)
+		fixes := c.fixes[bit]
+		shared := 0
+		for word := from; word < len(alive); word++ {
+			shared += bits.OnesCount64(alive[word] & fixes[word])
+		}
+		if shared > best {
+			split, best = bit, shared
+		}
+	}
+
+	mask := uint64(1) << uint(split)
+	half := c.scratch[depth+1]
+	for value, bitsValue := range [2]uint64{0, mask} {
+		compatible := c.compatible[split][value]
+		for word := from; word < len(alive); word++ {
+			half[word] = alive[word] & compatible[word]
+		}
+		c.claim(Pattern{Bits: region.Bits | bitsValue, Floating: region.Floating &^ mask}, depth+1, half, from)
+	}
+}
+
+func (m *SymbolicMemory) Sum() *big.Int {
+	patterns := make([]Pattern, len(m.cells))
+	for i, cell := range m.cells {
+		patterns[len(m.cells)-1-i] = cell.Pattern
+	}
+
+	c := newClaimer(patterns)
+	alive := c.scratch[0]
+	for i := range patterns {
+		alive[i/64] |= uint64(1) << uint(i%64)
+	}
+	c.claim(Pattern{Floating: wordMask}, 0, alive, 0)
+
+	sum := new(big.Int)
+	term := new(big.Int)
+	value := new(big.Int)
+	for i, owned := range c.owned {
+		term.SetUint64(owned)
+		value.SetUint64(m.cells[len(m.cells)-1-i].Value)
+		sum.Add(sum, term.Mul(term, value))
+	}
+
+	return sum
+}
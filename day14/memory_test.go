@@ -0,0 +1,106 @@
+package main
+
+import (
+	"math/big"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// generateMaskSets writes count values under masks that only set or float the
+// low maskBits bits, using addresses below 1<<addressBits so writes collide.
+func generateMaskSets(rng *rand.Rand, count int, floating int, maskBits int, addressBits uint) []MaskSet {
+	maskSets := []MaskSet{}
+	for len(maskSets)*4 < count {
+		mask := []byte(strings.Repeat("0", wordBits))
+		low := mask[wordBits-maskBits:]
+		for i := range low {
+			if rng.Intn(2) == 1 {
+				low[i] = '1'
+			}
+		}
+		for _, i := range rng.Perm(maskBits)[:floating] {
+			low[i] = 'X'
+		}
+
+		parsed, err := parseMask(string(mask))
+		if err != nil {
+			panic(err)
+		}
+
+		set := MaskSet{Mask: parsed}
+		for i := 0; i < 4; i++ {
+			set.Registry = append(set.Registry, Register{
+				Address: uint64(rng.Int63n(1 << addressBits)),
+				Value:   uint64(rng.Int63n(1 << wordBits)),
+			})
+		}
+		maskSets = append(maskSets, set)
+	}
+
+	return maskSets
+}
+
+// padFloating makes every mask float on extra high bits that no address or
+// mask otherwise touches. Each write then covers 1<<extra times as many addresses and the
+// sum grows by exactly that factor.
+func padFloating(maskSets []MaskSet, extra uint) []MaskSet {
+	padding := (uint64(1)<<extra - 1) << (wordBits - extra)
+	padded := []MaskSet{}
+	for _, set := range maskSets {
+		set.Mask.Floating |= padding
+		set.Mask.Or &^= padding
+		set.Mask.And |= padding
+		padded = append(padded, set)
+	}
+
+	return padded
+}
+
+func TestSymbolicSumMatchesExpanded(t *testing.T) {
+	rng := rand.New(rand.NewSource(14))
+	for _, floating := range []int{0, 1, 5, 9, 12} {
+		for _, addressBits := range []uint{4, 16, 36} {
+			maskSets := generateMaskSets(rng, 200, floating, wordBits, addressBits)
+
+			want, err := part2Expanded(maskSets)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := part2(maskSets); got.Cmp(new(big.Int).SetUint64(want)) != 0 {
+				t.Errorf("%d floating bits, %d address bits: symbolic sum %s, expanded sum %d", floating, addressBits, got, want)
+			}
+		}
+	}
+}
+
+func TestSymbolicSumWithManyFloatingBits(t *testing.T) {
+	if testing.Short() {
+		t.Skip("stress case")
+	}
+
+	const extra = 20
+	rng := rand.New(rand.NewSource(50))
+	for _, floating := range []int{10, 12} {
+		maskSets := generateMaskSets(rng, 400, floating, wordBits-extra, 12)
+		expanded, err := part2Expanded(maskSets)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		maskSets = padFloating(maskSets, extra)
+		if bits := maskSets[0].Mask.FloatingBits(); bits < 30 {
+			t.Fatalf("padded masks only have %d floating bits", bits)
+		}
+
+		want := new(big.Int).Lsh(new(big.Int).SetUint64(expanded), extra)
+		if got := part2(maskSets); got.Cmp(want) != 0 {
+			t.Errorf("%d+%d floating bits: symbolic sum %s, want %s", floating, extra, got, want)
+		}
+	}
+
+	// Random 31-bit masks fix only five bits each, so nearly every write
+	// overlaps every other one. This only has to finish.
+	part2(generateMaskSets(rng, 400, 31, wordBits, wordBits))
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	identRe   = regexp.MustCompile(`^[A-Za-z_]\w*$`)
+	labelRe   = regexp.MustCompile(`^([A-Za-z_]\w*):`)
+	operandRe = regexp.MustCompile(`^([+-]?)([A-Za-z_]\w*|\d+)$`)
+)
+
+var opcodes = map[string]bool{
+	"acc": true,
+	"jmp": true,
+	"nop": true,
+}
+
+type sourceLine struct {
+	Line    int
+	Address int
+	Code    string
+	Operand string
+}
+
+func assemble(r io.Reader) ([]*Instruction, error) {
+	scanner := bufio.NewScanner(r)
+	labels := map[string]int{}
+	constants := map[string]int{}
+	var lines []sourceLine
+
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := stripComment(scanner.Text())
+
+		for {
+			matches := labelRe.FindStringSubmatch(line)
+			if matches == nil {
+				break
+			}
+			if err := checkName(labels, constants, matches[1]); err != nil {
+				return nil, asmError(lineNo, err)
+			}
+			labels[matches[1]] = len(lines)
+			line = strings.TrimSpace(line[len(matches[0]):])
+		}
+
+		if line == "" {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if fields[0] == ".const" {
+			if len(fields) != 3 {
+				return nil, asmError(lineNo, errors.New("expected .const NAME VALUE"))
+			}
+			value, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return nil, asmError(lineNo, errors.New(fmt.Sprintf("invalid constant value: %s", fields[2])))
+			}
+			if err := checkName(labels, constants, fields[1]); err != nil {
+				return nil, asmError(lineNo, err)
+			}
+			constants[fields[1]] = value
+			continue
+		}
+
+		if !opcodes[fields[0]] {
+			return nil, asmError(lineNo, errors.New(fmt.Sprintf("unknown instruction: %s", fields[0])))
+		}
+		if len(fields) != 2 {
+			return nil, asmError(lineNo, errors.New(fmt.Sprintf("%s takes exactly one operand", fields[0])))
+		}
+
+		lines = append(lines, sourceLine{
+			Line:    lineNo,
+			Address: len(lines),
+			Code:    fields[0],
+			Operand: fields[1],
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	instructions := []*Instruction{}
+	for _, line := range lines {
+		amount, err := resolveOperand(line, labels, constants)
+		if err != nil {
+			return nil, asmError(line.Line, err)
+		}
+
+		instructions = append(instructions, &Instruction{
+			Code:   line.Code,
+			Amount: amount,
+		})
+	}
+
+	return instructions, nil
+}
+
+func stripComment(line string) string {
+	if i := strings.IndexAny(line, ";#"); i >= 0 {
+		line = line[:i]
+	}
+
+	return strings.TrimSpace(line)
+}
+
+func checkName(labels map[string]int, constants map[string]int, name string) error {
+	if !identRe.MatchString(name) {
+		return errors.New(fmt.Sprintf("invalid name: %s", name))
+	}
+	if opcodes[name] {
+		return errors.New(fmt.Sprintf("%s is a reserved word", name))
+	}
+	if _, ok := labels[name]; ok {
+		return errors.New(fmt.Sprintf("%s is already defined", name))
+	}
+	if _, ok := constants[name]; ok {
+		return errors.New(fmt.Sprintf("%s is already defined", name))
+	}
+
+	return nil
+}
+
+func resolveOperand(line sourceLine, labels map[string]int, constants map[string]int) (int, error) {
+	matches := operandRe.FindStringSubmatch(line.Operand)
+	if matches == nil {
+		return 0, errors.New(fmt.Sprintf("invalid operand: %s", line.Operand))
+	}
+
+	sign := 1
+	if matches[1] == "-" {
+		sign = -1
+	}
+
+	if !identRe.MatchString(matches[2]) {
+		value, err := strconv.Atoi(matches[2])
+		if err != nil {
+			return 0, err
+		}
+		return sign * value, nil
+	}
+
+	if value, ok := constants[matches[2]]; ok {
+		return sign * value, nil
+	}
+
+	if address, ok := labels[matches[2]]; ok {
+		if line.Code == "acc" {
+			return 0, errors.New(fmt.Sprintf("acc cannot take a label operand: %s", matches[2]))
+		}
+		if matches[1] != "" {
+			return 0, errors.New(fmt.Sprintf("label operands cannot be signed: %s", line.Operand))
+		}
+		return address - line.Address, nil
+	}
+
+	return 0, errors.New(fmt.Sprintf("undefined name: %s", matches[2]))
+}
+
+func asmError(line int, err error) error {
+	return errors.New(fmt.Sprintf("line %d: %s", line, err))
+}
+
+func disassemble(instructions []*Instruction) string {
+	targets := map[int]bool{}
+	for pc, instruction := range instructions {
+		if instruction.Code == "acc" {
+			continue
+		}
+
+		target := pc + instruction.Amount
+		if target >= 0 && target <= len(instructions) {
+			targets[target] = true
+		}
+	}
+
+	var sb strings.Builder
+	for pc, instruction := range instructions {
+		if targets[pc] {
+			sb.WriteString(fmt.Sprintf("%s:\n", labelName(pc)))
+		}
+
+		target := pc + instruction.Amount
+		if instruction.Code != "acc" && targets[target] {
+			sb.WriteString(fmt.Sprintf("    %s %s\n", instruction.Code, labelName(target)))
+		} else {
+			sb.WriteString(fmt.Sprintf("    %s %+d\n", instruction.Code, instruction.Amount))
+		}
+	}
+
+	if targets[len(instructions)] {
+		sb.WriteString(fmt.Sprintf("%s:\n", labelName(len(instructions))))
+	}
+
+	return sb.String()
+}
+
+func labelName(address int) string {
+	return fmt.Sprintf("L%d", address)
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+)
+
+var binaryMagic = []byte("HHC1")
+
+var opcodeBytes = map[string]byte{
+	"nop": 0,
+	"acc": 1,
+	"jmp": 2,
+}
+
+var byteOpcodes = map[byte]string{
+	0: "nop",
+	1: "acc",
+	2: "jmp",
+}
+
+func encodeProgram(instructions []*Instruction) ([]byte, error) {
+	buf := bytes.NewBuffer(append([]byte{}, binaryMagic...))
+	varint := make([]byte, binary.MaxVarintLen64)
+
+	n := binary.PutUvarint(varint, uint64(len(instructions)))
+	buf.Write(varint[:n])
+
+	for pc, instruction := range instructions {
+		op, ok := opcodeBytes[instruction.Code]
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("cannot encode instruction %s at address %d", instruction.Code, pc))
+		}
+
+		buf.WriteByte(op)
+		n := binary.PutVarint(varint, int64(instruction.Amount))
+		buf.Write(varint[:n])
+	}
+
+	return buf.Bytes(), nil
+}
+
+func decodeProgram(data []byte) ([]*Instruction, error) {
+	if !isBinaryProgram(data) {
+		return nil, errors.New("not a handheld console binary")
+	}
+
+	r := bytes.NewReader(data[len(binaryMagic):])
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("reading instruction count: %s", err))
+	}
+	if count > uint64(r.Len()) {
+		return nil, errors.New(fmt.Sprintf("instruction count %d exceeds data length", count))
+	}
+
+	instructions := make([]*Instruction, 0, count)
+	for pc := 0; uint64(pc) < count; pc++ {
+		op, err := r.ReadByte()
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("reading opcode at address %d: %s", pc, err))
+		}
+
+		code, ok := byteOpcodes[op]
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("unknown opcode %#x at address %d", op, pc))
+		}
+
+		amount, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("reading operand at address %d: %s", pc, err))
+		}
+
+		instructions = append(instructions, &Instruction{
+			Code:   code,
+			Amount: int(amount),
+		})
+	}
+
+	if r.Len() > 0 {
+		return nil, errors.New(fmt.Sprintf("%d trailing bytes after program", r.Len()))
+	}
+
+	return instructions, nil
+}
+
+func isBinaryProgram(data []byte) bool {
+	return bytes.HasPrefix(data, binaryMagic)
+}
+
+func loadProgram(path string) ([]*Instruction, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if isBinaryProgram(data) {
+		return decodeProgram(data)
+	}
+
+	return assemble(bytes.NewReader(data))
+}
+
+func writeBinary(instructions []*Instruction, path string) error {
+	data, err := encodeProgram(instructions)
+	if err != nil {
+		return err
+	}
+
+	decoded, err := decodeProgram(data)
+	if err != nil {
+		return err
+	}
+	if !sameProgram(instructions, decoded) {
+		return errors.New("binary encoding did not round-trip")
+	}
+
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+func sameProgram(a []*Instruction, b []*Instruction) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if *a[i] != *b[i] {
+			return false
+		}
+	}
+
+	return true
+}
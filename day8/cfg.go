@@ -0,0 +1,280 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+type Block struct {
+	ID          int
+	Start       int
+	End         int
+	Successors  []int
+	Reachable   bool
+	Terminates  bool
+	CriticalAt  []int
+	Exits       bool
+	OutOfBounds bool
+}
+
+type CFG struct {
+	Instructions []*Instruction
+	Blocks       []*Block
+	blockAt      map[int]int
+}
+
+func buildCFG(instructions []*Instruction) *CFG {
+	end := len(instructions)
+	leaders := map[int]bool{0: true}
+	for pc, instruction := range instructions {
+		if instruction.Code == "acc" {
+			continue
+		}
+
+		if target := pc + instruction.Amount; target >= 0 && target < end {
+			leaders[target] = true
+		}
+		if instruction.Code == "jmp" && pc+1 < end {
+			leaders[pc+1] = true
+		}
+	}
+
+	starts := []int{}
+	for pc := range leaders {
+		if pc < end {
+			starts = append(starts, pc)
+		}
+	}
+	sort.Ints(starts)
+
+	cfg := &CFG{
+		Instructions: instructions,
+		blockAt:      map[int]int{},
+	}
+	for i, start := range starts {
+		blockEnd := end
+		if i+1 < len(starts) {
+			blockEnd = starts[i+1]
+		}
+
+		cfg.blockAt[start] = i
+		cfg.Blocks = append(cfg.Blocks, &Block{
+			ID:    i,
+			Start: start,
+			End:   blockEnd,
+		})
+	}
+
+	for _, block := range cfg.Blocks {
+		last := block.End - 1
+		next := successor(instructions[last], last)
+		switch {
+		case next == end:
+			block.Exits = true
+		case next < 0 || next > end:
+			block.OutOfBounds = true
+		default:
+			block.Successors = append(block.Successors, cfg.blockAt[next])
+		}
+	}
+
+	cfg.analyse()
+
+	return cfg
+}
+
+func (c *CFG) analyse() {
+	if len(c.Blocks) == 0 {
+		return
+	}
+
+	queue := []int{0}
+	c.Blocks[0].Reachable = true
+	for len(queue) > 0 {
+		block := c.Blocks[queue[0]]
+		queue = queue[1:]
+		for _, id := range block.Successors {
+			if !c.Blocks[id].Reachable {
+				c.Blocks[id].Reachable = true
+				queue = append(queue, id)
+			}
+		}
+	}
+
+	terminates := terminatingAddresses(c.Instructions)
+	for _, block := range c.Blocks {
+		block.Terminates = terminates[block.Start]
+	}
+
+	// Only instructions the program actually executes can change whether it
+	// terminates, and the blocks holding them are exactly the reachable ones.
+	enter, leave := chainIntervals(c.Instructions)
+	t := trace(c.Instructions)
+	for _, step := range t.Steps {
+		if step.Code == "acc" {
+			continue
+		}
+
+		patched := &Instruction{Code: flipCode(step.Code), Amount: step.Amount}
+		next := successor(patched, step.PC)
+		_, ok := enter[next]
+		_, onChain := enter[step.PC]
+		passesFlip := onChain && enter[step.PC] <= enter[next] && leave[next] <= leave[step.PC]
+		flipTerminates := ok && !passesFlip
+		if flipTerminates != t.Finished {
+			block := c.Blocks[c.blockContaining(step.PC)]
+			block.CriticalAt = append(block.CriticalAt, step.PC)
+		}
+	}
+
+	for _, block := range c.Blocks {
+		sort.Ints(block.CriticalAt)
+	}
+}
+
+func (c *CFG) blockContaining(pc int) int {
+	return sort.Search(len(c.Blocks), func(i int) bool {
+		return c.Blocks[i].End > pc
+	})
+}
+
+// chainIntervals numbers the addresses that reach the end of the program in a
+// depth-first walk back from the end. An address lies on the path from next
+// to the end exactly when next's interval falls inside its own, so a flip
+// that jumps to next terminates only if next is numbered and the flipped
+// address is not on its path.
+func chainIntervals(instructions []*Instruction) (enter map[int]int, leave map[int]int) {
+	end := len(instructions)
+	predecessors := map[int][]int{}
+	for pc, instruction := range instructions {
+		next := successor(instruction, pc)
+		if next >= 0 && next <= end {
+			predecessors[next] = append(predecessors[next], pc)
+		}
+	}
+
+	enter, leave = map[int]int{}, map[int]int{}
+	clock := 0
+	type frame struct {
+		pc   int
+		next int
+	}
+	stack := []frame{{pc: end}}
+	enter[end] = clock
+	for len(stack) > 0 {
+		top := &stack[len(stack)-1]
+		if top.next < len(predecessors[top.pc]) {
+			prev := predecessors[top.pc][top.next]
+			top.next++
+			clock++
+			enter[prev] = clock
+			stack = append(stack, frame{pc: prev})
+			continue
+		}
+
+		leave[top.pc] = clock
+		stack = stack[:len(stack)-1]
+	}
+
+	return enter, leave
+}
+
+func (b *Block) label() string {
+	return fmt.Sprintf("B%d", b.ID)
+}
+
+func (b *Block) flags() []string {
+	flags := []string{}
+	if !b.Reachable {
+		flags = append(flags, "unreachable")
+	}
+	if !b.Terminates {
+		flags = append(flags, "never terminates")
+	}
+	if len(b.CriticalAt) > 0 {
+		addresses := []string{}
+		for _, pc := range b.CriticalAt {
+			addresses = append(addresses, fmt.Sprint(pc))
+		}
+		flags = append(flags, fmt.Sprintf("flip changes termination at %s", strings.Join(addresses, ",")))
+	}
+
+	return flags
+}
+
+func (c *CFG) WriteText(w io.Writer) error {
+	var sb strings.Builder
+	for _, block := range c.Blocks {
+		sb.WriteString(fmt.Sprintf("%s [%d-%d]", block.label(), block.Start, block.End-1))
+		if flags := block.flags(); len(flags) > 0 {
+			sb.WriteString(fmt.Sprintf(" (%s)", strings.Join(flags, "; ")))
+		}
+		sb.WriteString("\n")
+
+		for pc := block.Start; pc < block.End; pc++ {
+			sb.WriteString(fmt.Sprintf("    %4d  %s %+d\n", pc, c.Instructions[pc].Code, c.Instructions[pc].Amount))
+		}
+
+		for _, id := range block.Successors {
+			sb.WriteString(fmt.Sprintf("    -> %s\n", c.Blocks[id].label()))
+		}
+		if block.Exits {
+			sb.WriteString("    -> exit\n")
+		}
+		if block.OutOfBounds {
+			sb.WriteString("    -> out of bounds\n")
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func (c *CFG) WriteDOT(w io.Writer) error {
+	var sb strings.Builder
+	sb.WriteString("digraph program {\n")
+	sb.WriteString("    node [shape=box fontname=monospace];\n")
+	sb.WriteString("    exit [shape=doublecircle];\n")
+
+	for _, block := range c.Blocks {
+		lines := []string{fmt.Sprintf("%s [%d-%d]", block.label(), block.Start, block.End-1)}
+		for pc := block.Start; pc < block.End; pc++ {
+			lines = append(lines, fmt.Sprintf("%d: %s %+d", pc, c.Instructions[pc].Code, c.Instructions[pc].Amount))
+		}
+		lines = append(lines, block.flags()...)
+
+		attrs := []string{fmt.Sprintf("label=\"%s\\l\"", strings.Join(lines, "\\l"))}
+		switch {
+		case !block.Reachable:
+			attrs = append(attrs, "style=dashed", "color=gray")
+		case !block.Terminates:
+			attrs = append(attrs, "color=red")
+		}
+		if len(block.CriticalAt) > 0 {
+			attrs = append(attrs, "penwidth=3")
+		}
+		sb.WriteString(fmt.Sprintf("    %s [%s];\n", block.label(), strings.Join(attrs, " ")))
+	}
+
+	for _, block := range c.Blocks {
+		last := c.Instructions[block.End-1]
+		style := "solid"
+		if last.Code != "jmp" {
+			style = "dotted"
+		}
+
+		for _, id := range block.Successors {
+			sb.WriteString(fmt.Sprintf("    %s -> %s [style=%s];\n", block.label(), c.Blocks[id].label(), style))
+		}
+		if block.Exits {
+			sb.WriteString(fmt.Sprintf("    %s -> exit [style=%s];\n", block.label(), style))
+		}
+	}
+
+	sb.WriteString("}\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
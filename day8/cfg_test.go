@@ -0,0 +1,53 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func criticalAddresses(t *testing.T, source string) []int {
+	instructions, err := assemble(strings.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	critical := []int{}
+	for _, block := range buildCFG(instructions).Blocks {
+		critical = append(critical, block.CriticalAt...)
+	}
+
+	return critical
+}
+
+func TestCriticalAt(t *testing.T) {
+	cases := []struct {
+		name   string
+		source string
+		want   []int
+	}{
+		{
+			name:   "terminating program",
+			source: "nop +2\njmp +3\njmp +0\nacc +1\n",
+			want:   []int{0, 1},
+		},
+		{
+			name:   "looping program",
+			source: "nop +0\nacc +1\njmp +4\nacc +3\njmp -3\nacc -99\nacc +1\njmp -4\nacc +6\n",
+			want:   []int{7},
+		},
+		{
+			name:   "flip back into its own path",
+			source: "jmp +2\nacc +1\nnop -1\n",
+			want:   []int{2},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := criticalAddresses(t, c.source); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("critical addresses %v, want %v", got, c.want)
+			}
+		})
+	}
+}
@@ -3,11 +3,13 @@ package main
 import (
 	"bufio"
 	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"os"
 	"regexp"
 	"strconv"
+	"strings"
 )
 
 type Instruction struct {
@@ -52,61 +54,119 @@ func part1(instructions []*Instruction) (acc int) {
 }
 
 func part2(instructions []*Instruction) (acc int, err error) {
-	for _, instruction := range instructions {
-		if instruction.Code == "acc" {
-			continue
-		}
+	repairs, err := findRepairs(instructions)
+	if err != nil {
+		return 0, err
+	}
 
-		flipInstruction(instruction)
-		acc, finished := run(instructions)
-		if finished {
-			return acc, nil
-		} else {
-			flipInstruction(instruction)
-		}
+	switch len(repairs) {
+	case 0:
+		return 0, errors.New("no single jmp/nop flip makes the program terminate")
+	case 1:
+		return repairs[0].Acc, nil
 	}
 
-	return 0, errors.New("flipped all possible instructions and program still failed")
+	candidates := []string{}
+	for _, repair := range repairs {
+		candidates = append(candidates, repair.String())
+	}
+
+	return 0, errors.New(fmt.Sprintf("found %d possible repairs: %s", len(repairs), strings.Join(candidates, "; ")))
 }
 
-func flipInstruction(instruction *Instruction) {
-	switch instruction.Code {
+func flipCode(code string) string {
+	switch code {
 	case "nop":
-		instruction.Code = "jmp"
+		return "jmp"
 	case "jmp":
-		instruction.Code = "nop"
+		return "nop"
 	}
+
+	return code
 }
 
 func run(instructions []*Instruction) (acc int, finished bool) {
-	i := 0
-	called := map[int]int{}
-	for i < len(instructions) {
-		if called[i] > 0 {
-			return acc, false
-		}
-		called[i]++
-
-		switch instructions[i].Code {
-		case "acc":
-			acc = acc + instructions[i].Amount
-			i++
-		case "jmp":
-			i = i + instructions[i].Amount
-		case "nop":
-			i++
+	t := trace(instructions)
+	return t.Acc, t.Finished
+}
+
+func writeTrace(instructions []*Instruction, path string, format string) error {
+	out := os.Stdout
+	if path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
 		}
+		defer f.Close()
+		out = f
+	}
+
+	t := trace(instructions)
+	if err := t.Write(out, format); err != nil {
+		return err
+	}
+
+	if t.Loop != nil {
+		return t.Loop.WriteReport(os.Stderr)
 	}
 
-	return acc, true
+	return nil
+}
+
+func writeCFG(instructions []*Instruction, format string) error {
+	cfg := buildCFG(instructions)
+	switch format {
+	case "text":
+		return cfg.WriteText(os.Stdout)
+	case "dot":
+		return cfg.WriteDOT(os.Stdout)
+	}
+
+	return errors.New(fmt.Sprintf("unknown control-flow graph format: %s", format))
 }
 
 func main() {
-	instructions, err := getInstructions()
+	tracePath := flag.String("trace", "", "write an execution trace of part 1 to this file (- for stdout)")
+	traceFormat := flag.String("trace-format", "text", "trace format: text or json")
+	programPath := flag.String("program", "", "load an assembly source or binary program instead of input.txt")
+	disasm := flag.Bool("disasm", false, "print the program as assembly with resolved labels")
+	encodePath := flag.String("encode", "", "write the program in binary format to this file")
+	cfgFormat := flag.String("cfg", "", "print the control-flow graph as text or dot")
+	flag.Parse()
+
+	var instructions []*Instruction
+	var err error
+	if *programPath != "" {
+		instructions, err = loadProgram(*programPath)
+	} else {
+		instructions, err = getInstructions()
+	}
 	if err != nil {
 		log.Fatalln(fmt.Sprintf("Error fetching instructions: %s", err))
 	}
 
+	if *disasm {
+		fmt.Print(disassemble(instructions))
+	}
+
+	if *cfgFormat != "" {
+		if err := writeCFG(instructions, *cfgFormat); err != nil {
+			log.Fatalln(fmt.Sprintf("Error writing control-flow graph: %s", err))
+		}
+	}
+
+	if *encodePath != "" {
+		if err := writeBinary(instructions, *encodePath); err != nil {
+			log.Fatalln(fmt.Sprintf("Error encoding program: %s", err))
+		}
+	}
+
+	if *tracePath != "" {
+		if err := writeTrace(instructions, *tracePath, *traceFormat); err != nil {
+			log.Fatalln(fmt.Sprintf("Error writing trace: %s", err))
+		}
+	}
+
 	log.Println(fmt.Sprintf("The accumulator has a value of %d in part 1", part1(instructions)))
 	acc, err := part2(instructions)
 	if err != nil {
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+type Repair struct {
+	Address int
+	From    string
+	To      string
+	Acc     int
+}
+
+func successor(instruction *Instruction, pc int) int {
+	_, next := execute(instruction, 0, pc)
+	return next
+}
+
+func terminatingAddresses(instructions []*Instruction) map[int]bool {
+	end := len(instructions)
+	predecessors := map[int][]int{}
+	for pc, instruction := range instructions {
+		next := successor(instruction, pc)
+		if next >= 0 && next <= end {
+			predecessors[next] = append(predecessors[next], pc)
+		}
+	}
+
+	terminates := map[int]bool{end: true}
+	queue := []int{end}
+	for len(queue) > 0 {
+		pc := queue[0]
+		queue = queue[1:]
+
+		for _, prev := range predecessors[pc] {
+			if !terminates[prev] {
+				terminates[prev] = true
+				queue = append(queue, prev)
+			}
+		}
+	}
+
+	return terminates
+}
+
+func findRepairs(instructions []*Instruction) ([]Repair, error) {
+	t := trace(instructions)
+	if t.Finished {
+		return nil, errors.New("program already terminates, nothing to repair")
+	}
+
+	terminates := terminatingAddresses(instructions)
+
+	var repairs []Repair
+	for _, step := range t.Steps {
+		if step.Code == "acc" {
+			continue
+		}
+
+		patched := &Instruction{Code: flipCode(step.Code), Amount: step.Amount}
+		if !terminates[successor(patched, step.PC)] {
+			continue
+		}
+
+		acc, _ := runPatched(instructions, step.PC, patched)
+		repairs = append(repairs, Repair{
+			Address: step.PC,
+			From:    step.Code,
+			To:      patched.Code,
+			Acc:     acc,
+		})
+	}
+
+	return repairs, nil
+}
+
+func runPatched(instructions []*Instruction, address int, patched *Instruction) (acc int, finished bool) {
+	pc := 0
+	called := map[int]bool{}
+	for pc >= 0 && pc < len(instructions) {
+		if called[pc] {
+			return acc, false
+		}
+		called[pc] = true
+
+		instruction := instructions[pc]
+		if pc == address {
+			instruction = patched
+		}
+		acc, pc = execute(instruction, acc, pc)
+	}
+
+	return acc, pc == len(instructions)
+}
+
+func (r Repair) String() string {
+	return fmt.Sprintf("flip %s to %s at address %d (acc %d)", r.From, r.To, r.Address, r.Acc)
+}
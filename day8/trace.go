@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+type Step struct {
+	PC        int    `json:"pc"`
+	Code      string `json:"code"`
+	Amount    int    `json:"amount"`
+	AccBefore int    `json:"acc_before"`
+	AccAfter  int    `json:"acc_after"`
+}
+
+type Loop struct {
+	Entry int
+	Body  []Step
+	Path  []Step
+}
+
+type Trace struct {
+	Steps    []Step
+	Loop     *Loop
+	Acc      int
+	ExitPC   int
+	Finished bool
+}
+
+func trace(instructions []*Instruction) *Trace {
+	t := &Trace{}
+	pc := 0
+	seen := map[int]int{}
+
+	for pc >= 0 && pc < len(instructions) {
+		if first, ok := seen[pc]; ok {
+			t.Loop = &Loop{
+				Entry: pc,
+				Body:  t.Steps[first:],
+				Path:  t.Steps[:first],
+			}
+			break
+		}
+		seen[pc] = len(t.Steps)
+
+		step := Step{
+			PC:        pc,
+			Code:      instructions[pc].Code,
+			Amount:    instructions[pc].Amount,
+			AccBefore: t.Acc,
+		}
+		t.Acc, pc = execute(instructions[pc], t.Acc, pc)
+		step.AccAfter = t.Acc
+
+		t.Steps = append(t.Steps, step)
+	}
+
+	t.ExitPC = pc
+	t.Finished = pc == len(instructions)
+
+	return t
+}
+
+func execute(instruction *Instruction, acc int, pc int) (int, int) {
+	switch instruction.Code {
+	case "acc":
+		return acc + instruction.Amount, pc + 1
+	case "jmp":
+		return acc, pc + instruction.Amount
+	}
+
+	return acc, pc + 1
+}
+
+func (s Step) String() string {
+	return fmt.Sprintf("%4d  %s %+d  acc %d -> %d", s.PC, s.Code, s.Amount, s.AccBefore, s.AccAfter)
+}
+
+func (t *Trace) WriteText(w io.Writer) error {
+	for _, step := range t.Steps {
+		if _, err := fmt.Fprintln(w, step); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *Trace) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	for _, step := range t.Steps {
+		if err := enc.Encode(step); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *Trace) Write(w io.Writer, format string) error {
+	switch format {
+	case "text":
+		return t.WriteText(w)
+	case "json":
+		return t.WriteJSON(w)
+	}
+
+	return errors.New(fmt.Sprintf("unknown trace format: %s", format))
+}
+
+func (l *Loop) WriteReport(w io.Writer) error {
+	lines := []string{
+		fmt.Sprintf("infinite loop entered at address %d after %d steps", l.Entry, len(l.Path)),
+		fmt.Sprintf("path into loop (%d instructions):", len(l.Path)),
+	}
+	for _, step := range l.Path {
+		lines = append(lines, step.String())
+	}
+
+	lines = append(lines, fmt.Sprintf("loop body (%d instructions):", len(l.Body)))
+	for _, step := range l.Body {
+		lines = append(lines, step.String())
+	}
+
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+type Invalid struct {
+	Index int
+	Value int
+}
+
+type Analyser struct {
+	Preamble int
+}
+
+type window struct {
+	values []int
+	counts map[int]int
+	next   int
+}
+
+func newWindow(size int) *window {
+	return &window{
+		values: make([]int, 0, size),
+		counts: map[int]int{},
+	}
+}
+
+func (w *window) full() bool {
+	return len(w.values) == cap(w.values)
+}
+
+func (w *window) push(value int) {
+	if !w.full() {
+		w.values = append(w.values, value)
+	} else {
+		old := w.values[w.next]
+		w.counts[old]--
+		if w.counts[old] == 0 {
+			delete(w.counts, old)
+		}
+		w.values[w.next] = value
+		w.next = (w.next + 1) % len(w.values)
+	}
+
+	w.counts[value]++
+}
+
+func (w *window) hasPair(sum int) bool {
+	for _, addend := range w.values {
+		difference := sum - addend
+		if difference != addend && w.counts[difference] > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (a Analyser) validate() error {
+	if a.Preamble < 2 {
+		return errors.New(fmt.Sprintf("preamble must be at least 2, got %d", a.Preamble))
+	}
+
+	return nil
+}
+
+type validator struct {
+	window *window
+	offset int
+}
+
+func (a Analyser) newValidator() (*validator, error) {
+	if err := a.validate(); err != nil {
+		return nil, err
+	}
+
+	return &validator{window: newWindow(a.Preamble)}, nil
+}
+
+func (v *validator) check(number int) (Invalid, bool) {
+	offset := v.offset
+	v.offset++
+
+	valid := !v.window.full() || v.window.hasPair(number)
+	v.window.push(number)
+
+	return Invalid{Index: offset, Value: number}, valid
+}
+
+func (a Analyser) scan(numbers []int, all bool) ([]Invalid, error) {
+	if len(numbers) < a.Preamble {
+		return nil, errors.New(fmt.Sprintf("need at least %d numbers for the preamble, got %d", a.Preamble, len(numbers)))
+	}
+
+	v, err := a.newValidator()
+	if err != nil {
+		return nil, err
+	}
+
+	var invalid []Invalid
+	for _, number := range numbers {
+		if result, valid := v.check(number); !valid {
+			invalid = append(invalid, result)
+			if !all {
+				break
+			}
+		}
+	}
+
+	return invalid, nil
+}
+
+func (a Analyser) First(numbers []int) (Invalid, error) {
+	invalid, err := a.scan(numbers, false)
+	if err != nil {
+		return Invalid{}, err
+	}
+	if len(invalid) == 0 {
+		return Invalid{}, errors.New("no numbers failed the encryption check")
+	}
+
+	return invalid[0], nil
+}
+
+func (a Analyser) All(numbers []int) ([]Invalid, error) {
+	return a.scan(numbers, true)
+}
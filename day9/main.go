@@ -3,6 +3,7 @@ package main
 import (
 	"bufio"
 	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"os"
@@ -34,90 +35,93 @@ func getNumbers() (numbers []int, err error) {
 	return numbers, nil
 }
 
-func part1(numbers []int) (int, error) {
-	buffer := numbers[0:25]
-	for i := 25; i < len(numbers)-25; i++ {
-		valid := checkAddends(numbers[i], buffer)
-		if !valid {
-			return numbers[i], nil
-		}
-
-		buffer = append(buffer[1:], numbers[i])
+func part1(numbers []int, preamble int) (int, error) {
+	invalid, err := Analyser{Preamble: preamble}.First(numbers)
+	if err != nil {
+		return 0, err
 	}
 
-	return 0, errors.New("no numbers failed the encryption check")
+	return invalid.Value, nil
 }
 
-func checkAddends(sum int, addends []int) bool {
-	for _, addend1 := range addends {
-		difference := sum - addend1
-		if difference == addend1 {
-			continue
-		}
-		for _, addend2 := range addends {
-			if difference == addend2 {
-				return true
-			}
-		}
+func part2(target int, numbers []int, minLength int) (int, error) {
+	ranges, err := findRanges(target, numbers, minLength)
+	if err != nil {
+		return 0, err
 	}
 
-	return false
-}
-
-func part2(target int, numbers []int) (int, error) {
-	buffer := []int{}
-	for i := 0; i < len(numbers); i++ {
-		sum := numbers[i]
-
-		for j := 0; sum < target && j < len(numbers); j++ {
-			buffer = append(buffer, numbers[i+j])
-			sum = calculateSum(buffer)
-		}
-
-		if sum == target {
-			lowest := buffer[0]
-			highest := buffer[0]
-			for _, i := range buffer {
-				if i < lowest {
-					lowest = i
-				}
-
-				if i > highest {
-					highest = i
-				}
-			}
-
-			return lowest + highest, nil
-		}
-
-		buffer = []int{}
+	if len(ranges) == 0 {
+		return 0, errors.New(fmt.Sprintf("got to the end of input with no valid sum for %d", target))
 	}
 
-	return 0, errors.New(fmt.Sprintf("got to the end of input with no valid sum for %d", target))
+	return ranges[0].Weakness, nil
 }
 
-func calculateSum(numbers []int) (sum int) {
-	for _, number := range numbers {
-		sum = sum + number
+func streamNumbers(path string, preamble int) error {
+	in := os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
 	}
 
-	return sum
+	return Analyser{Preamble: preamble}.ScanReader(in, func(number Invalid) error {
+		log.Println(fmt.Sprintf("Number %d at offset %d fails the encryption check", number.Value, number.Index))
+		return nil
+	})
 }
 
 func main() {
+	preamble := flag.Int("preamble", 25, "number of preceding values each number must be a sum of")
+	all := flag.Bool("all", false, "report every number which fails the encryption check")
+	minLength := flag.Int("min-length", 2, "minimum length of a contiguous range summing to the invalid number")
+	allRanges := flag.Bool("all-ranges", false, "report every contiguous range summing to the invalid number")
+	stream := flag.String("stream", "", "stream numbers from this file (- for stdin) and report invalid numbers as they arrive")
+	flag.Parse()
+
+	if *stream != "" {
+		if err := streamNumbers(*stream, *preamble); err != nil {
+			log.Fatalln(fmt.Sprintf("Error streaming numbers: %s", err))
+		}
+		return
+	}
+
 	numbers, err := getNumbers()
 	if err != nil {
 		log.Fatalln(fmt.Sprintf("Error fetching instructions: %s", err))
 	}
 
-	notSum, err := part1(numbers)
+	if *all {
+		invalid, err := Analyser{Preamble: *preamble}.All(numbers)
+		if err != nil {
+			log.Fatalln(fmt.Sprintf("Error checking numbers: %s", err))
+		}
+		for _, number := range invalid {
+			log.Println(fmt.Sprintf("Number %d at index %d fails the encryption check", number.Value, number.Index))
+		}
+	}
+
+	notSum, err := part1(numbers, *preamble)
 	if err != nil {
 		log.Fatalln(fmt.Sprintf("Error finding the weakness in part 1: %s", err))
 	}
 
 	log.Println(fmt.Sprintf("The first number which fails the encryption check is %d in part 1", notSum))
 
-	weakness, err := part2(notSum, numbers)
+	if *allRanges {
+		ranges, err := findRanges(notSum, numbers, *minLength)
+		if err != nil {
+			log.Fatalln(fmt.Sprintf("Error finding ranges: %s", err))
+		}
+		for _, r := range ranges {
+			log.Println(fmt.Sprintf("Range %d-%d sums to %d (min %d, max %d, weakness %d)", r.Start, r.End, notSum, r.Min, r.Max, r.Weakness))
+		}
+	}
+
+	weakness, err := part2(notSum, numbers, *minLength)
 	if err != nil {
 		log.Fatalln(fmt.Sprintf("Error finding the weakness in part 2: %s", err))
 	}
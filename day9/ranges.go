@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+type Range struct {
+	Start    int
+	End      int
+	Min      int
+	Max      int
+	Weakness int
+}
+
+func findRanges(target int, numbers []int, minLength int) ([]Range, error) {
+	if minLength < 1 {
+		return nil, errors.New(fmt.Sprintf("minimum range length must be at least 1, got %d", minLength))
+	}
+
+	prefix := make([]int, len(numbers)+1)
+	for i, number := range numbers {
+		prefix[i+1] = prefix[i] + number
+	}
+
+	starts := map[int][]int{}
+	ranges := []Range{}
+	for end := minLength; end <= len(numbers); end++ {
+		start := end - minLength
+		starts[prefix[start]] = append(starts[prefix[start]], start)
+
+		for _, start := range starts[prefix[end]-target] {
+			ranges = append(ranges, newRange(numbers, start, end-1))
+		}
+	}
+
+	sort.Slice(ranges, func(i, j int) bool {
+		if ranges[i].Start != ranges[j].Start {
+			return ranges[i].Start < ranges[j].Start
+		}
+		return ranges[i].End < ranges[j].End
+	})
+
+	return ranges, nil
+}
+
+func newRange(numbers []int, start int, end int) Range {
+	r := Range{
+		Start: start,
+		End:   end,
+		Min:   numbers[start],
+		Max:   numbers[start],
+	}
+
+	for _, number := range numbers[start : end+1] {
+		if number < r.Min {
+			r.Min = number
+		}
+		if number > r.Max {
+			r.Max = number
+		}
+	}
+	r.Weakness = r.Min + r.Max
+
+	return r
+}
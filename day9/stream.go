@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+func (a Analyser) ScanReader(r io.Reader, emit func(Invalid) error) error {
+	v, err := a.newValidator()
+	if err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		number, err := strconv.Atoi(text)
+		if err != nil {
+			return errors.New(fmt.Sprintf("line %d: %s", line, err))
+		}
+
+		if result, valid := v.check(number); !valid {
+			if err := emit(result); err != nil {
+				return err
+			}
+		}
+	}
+
+	return scanner.Err()
+}
+
+func (a Analyser) ScanChan(numbers <-chan int) (<-chan Invalid, error) {
+	v, err := a.newValidator()
+	if err != nil {
+		return nil, err
+	}
+
+	invalid := make(chan Invalid)
+	go func() {
+		defer close(invalid)
+		for number := range numbers {
+			if result, valid := v.check(number); !valid {
+				invalid <- result
+			}
+		}
+	}()
+
+	return invalid, nil
+}